@@ -2,18 +2,29 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
+	"goterm/backend/internal/batch"
+	"goterm/backend/internal/bundle"
 	"goterm/backend/internal/common"
+	"goterm/backend/internal/forward"
 	"goterm/backend/internal/metrics"
 	"goterm/backend/internal/mysql"
 	"goterm/backend/internal/profiles"
+	"goterm/backend/internal/profiles/sshconfig"
 	"goterm/backend/internal/security/hostkey"
 	"goterm/backend/internal/security/keyring"
+	"goterm/backend/internal/security/sshagent"
+	"goterm/backend/internal/security/sshkeys"
 	"goterm/backend/internal/session"
 	"goterm/backend/internal/sftp"
+	"goterm/backend/internal/snippets"
 	"goterm/backend/internal/storage/sqlite"
 	"goterm/backend/internal/terminal"
 	"goterm/backend/internal/transfer"
@@ -22,16 +33,32 @@ import (
 type App struct {
 	ctx context.Context
 
-	store       profiles.Store
-	mysqlStore  mysql.Store
-	sessions    *session.Manager
-	terminals   *terminal.Hub
-	files       *sftp.Service
-	transfers   *transfer.Queue
-	mysql       *mysql.Manager
-	prompts     *HostKeyPromptManager
-	dataDir     string
-	hostKeyPath string
+	store        profiles.Store
+	mysqlStore   mysql.Store
+	snippetStore snippets.Store
+	sessions     *session.Manager
+	terminals    *terminal.Hub
+	files        *sftp.Service
+	transfers    *transfer.Queue
+	batch        *batch.Service
+	snippets     *snippets.Service
+	forwards     *forward.Manager
+	agent        *sshagent.Agent
+	mysql        *mysql.Manager
+	bundles      *bundle.Service
+	prompts      *HostKeyPromptManager
+	authPrompts  *AuthPromptManager
+	broadcasts   *BroadcastPromptManager
+	vault        *keyring.Vault
+	keys         *sshkeys.Manager
+	deployer     *sshkeys.Deployer
+	recordRules  *terminal.RecordRules
+	triggerRules *terminal.TriggerRules
+	player       *terminal.Player
+	emitter      common.Emitter
+	dataDir      string
+	recordDir    string
+	hostKeyPath  string
 }
 
 func NewApp(cfg Config) (*App, error) {
@@ -72,27 +99,116 @@ func NewApp(cfg Config) (*App, error) {
 		mysqlStore = sqliteStore
 	}
 
+	snippetStore := cfg.SnippetStore
+	if snippetStore == nil {
+		sqliteStore, err := sqlite.OpenSnippetStore(filepath.Join(dataDir, "snippets.db"))
+		if err != nil {
+			return nil, err
+		}
+		snippetStore = sqliteStore
+	}
+
+	// Fall back to the encrypted vault when there is no OS credential store,
+	// and keep using it once secrets were moved into it.
+	vault := keyring.NewVault(filepath.Join(dataDir, "vault.json"))
+	if vault.Exists() || !keyring.SystemAvailable() {
+		keyring.SetBackend(vault)
+	}
+	vault.OnLock(func() {
+		emitter.Emit("vault:state", vault.Status())
+	})
+
 	promptManager := NewHostKeyPromptManager(emitter)
 
 	verifier := &hostkey.Verifier{
-		Path:   hostKeyPath,
-		Policy: hostkey.PolicyAsk,
-		Prompt: promptManager.Ask,
+		Path:    hostKeyPath,
+		Policy:  hostkey.PolicyAsk,
+		Prompt:  promptManager.Ask,
+		Changed: promptManager.AskChanged,
 	}
 
-	sessions := session.NewManager(store, verifier, emitter)
+	agent := sshagent.New()
+	sessions := session.NewManager(store, verifier, agent, emitter)
+
+	authPrompts := NewAuthPromptManager(emitter)
+	sessions.SetChallengeFunc(authPrompts.Ask)
 
+	forwards := forward.NewManager(sessions, emitter)
+	sessions.AddStateListener(func(event session.StateEvent) {
+		if event.SessionID == "" {
+			return
+		}
+		switch event.State {
+		case "connected":
+			forwards.SessionRestored(event.SessionID)
+		case "disconnected":
+			forwards.SessionClosed(event.SessionID)
+		}
+	})
+
+	recordDir := filepath.Join(dataDir, "recordings")
+	recordRules, err := terminal.NewRecordRules(filepath.Join(dataDir, "recording-rules.json"))
+	if err != nil {
+		return nil, err
+	}
+	triggerRules, err := terminal.NewTriggerRules(filepath.Join(dataDir, "triggers.json"))
+	if err != nil {
+		return nil, err
+	}
+	terminals := terminal.NewHub(sessions, emitter)
+	terminals.SetTriggerFunc(func(sessionID string) []terminal.TriggerRule {
+		profileID, err := sessions.ProfileID(sessionID)
+		if err != nil {
+			return triggerRules.ForProfile(terminal.AllProfiles)
+		}
+		return triggerRules.ForProfile(profileID)
+	})
+	terminals.SetRecordingDir(recordDir)
+	broadcastPrompts := NewBroadcastPromptManager(emitter)
+	terminals.SetBroadcastGuard(broadcastPrompts.Guard(sessions.ProfileID, store))
+	terminals.SetAutoRecordFunc(func(sessionID string) (terminal.RecordOptions, bool) {
+		profileID, err := sessions.ProfileID(sessionID)
+		if err != nil {
+			return terminal.RecordOptions{}, false
+		}
+		profile, err := store.Get(context.Background(), profileID)
+		if err != nil {
+			return terminal.RecordOptions{}, false
+		}
+		opts, ok := recordRules.Match(profile.Group)
+		opts.Title = profile.Name
+		return opts, ok
+	})
+
+	files := sftp.NewService(sessions)
+	runner := batch.NewService(sessions, store, emitter)
 	app := &App{
-		store:       store,
-		mysqlStore:  mysqlStore,
-		sessions:    sessions,
-		terminals:   terminal.NewHub(sessions, emitter),
-		files:       sftp.NewService(sessions),
-		transfers:   transfer.NewQueue(sessions, emitter, 2),
-		mysql:       mysql.NewManager(mysqlStore, sessions),
-		prompts:     promptManager,
-		dataDir:     dataDir,
-		hostKeyPath: hostKeyPath,
+		store:        store,
+		mysqlStore:   mysqlStore,
+		snippetStore: snippetStore,
+		sessions:     sessions,
+		terminals:    terminals,
+		files:        files,
+		transfers:    transfer.NewQueue(sessions, emitter, 2),
+		batch:        runner,
+		snippets:     snippets.NewService(snippetStore, terminals, runner),
+		forwards:     forwards,
+		agent:        agent,
+		mysql:        mysql.NewManager(mysqlStore, sessions),
+		bundles:      bundle.NewService(store, mysqlStore, hostKeyPath),
+		prompts:      promptManager,
+		authPrompts:  authPrompts,
+		broadcasts:   broadcastPrompts,
+		vault:        vault,
+		keys:         sshkeys.NewManager(filepath.Join(dataDir, "keys")),
+		deployer:     sshkeys.NewDeployer(sessions, files, store),
+		recordRules:  recordRules,
+		triggerRules: triggerRules,
+		player:       terminal.NewPlayer(emitter),
+		emitter:      emitter,
+		dataDir:      dataDir,
+		recordDir:    recordDir,
+		hostKeyPath:  hostKeyPath,
 	}
 
 	return app, nil
@@ -106,10 +222,37 @@ func (a *App) ProfilesList() ([]profiles.Profile, error) {
 	return a.store.List(a.ctxOrBackground())
 }
 
+func (a *App) ProfilesSearch(query profiles.SearchQuery) ([]profiles.Profile, error) {
+	return a.store.Search(a.ctxOrBackground(), query)
+}
+
 func (a *App) ProfilesSave(profile profiles.Profile) (string, error) {
 	return a.store.Save(a.ctxOrBackground(), profile)
 }
 
+func (a *App) ProfilesImportSSHConfig(options sshconfig.Options) (sshconfig.Report, error) {
+	return sshconfig.Import(a.ctxOrBackground(), a.store, options)
+}
+
+func (a *App) BundleExport(path string, options bundle.ExportOptions) error {
+	if options.Format == "" {
+		options.Format = bundle.FormatFromPath(path)
+	}
+	data, err := a.bundles.Export(a.ctxOrBackground(), options)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (a *App) BundleImport(path string, options bundle.ImportOptions) (bundle.ImportReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bundle.ImportReport{}, err
+	}
+	return a.bundles.Import(a.ctxOrBackground(), data, options)
+}
+
 func (a *App) ProfilesDelete(profileID string) error {
 	return a.store.Delete(a.ctxOrBackground(), profileID)
 }
@@ -210,6 +353,238 @@ func (a *App) TerminalClose(termID string) error {
 	return a.terminals.Close(termID)
 }
 
+func (a *App) TerminalList() []terminal.TerminalInfo {
+	return a.terminals.List()
+}
+
+// TerminalAttach returns the buffered output of a running terminal so a view
+// can rebuild its screen after a reload.
+func (a *App) TerminalAttach(termID string) (terminal.Snapshot, error) {
+	return a.terminals.Attach(termID)
+}
+
+// TerminalDetach leaves the terminal running without a view until it is
+// attached again or closed.
+func (a *App) TerminalDetach(termID string) error {
+	return a.terminals.Detach(termID)
+}
+
+func (a *App) TerminalSnapshot(termID string) (terminal.Snapshot, error) {
+	return a.terminals.Snapshot(termID)
+}
+
+// ExecStart runs a command on several profiles and returns the job ID.
+func (a *App) ExecStart(request batch.Request) (string, error) {
+	return a.batch.Start(a.ctxOrBackground(), request)
+}
+
+func (a *App) ExecCancel(jobID string) error {
+	return a.batch.Cancel(jobID)
+}
+
+func (a *App) ExecSummary(jobID string) (batch.Summary, error) {
+	return a.batch.Summary(jobID)
+}
+
+func (a *App) ExecForget(jobID string) error {
+	return a.batch.Forget(jobID)
+}
+
+// ExecExport writes the job's summary table to path as JSON or CSV. An empty
+// format is taken from the file extension.
+func (a *App) ExecExport(jobID, path, format string) error {
+	if format == "" {
+		format = batch.FormatFromPath(path)
+	}
+	data, err := a.batch.Export(jobID, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (a *App) SnippetsList() ([]snippets.Snippet, error) {
+	return a.snippetStore.List(a.ctxOrBackground())
+}
+
+func (a *App) SnippetsGet(id string) (snippets.Snippet, error) {
+	return a.snippetStore.Get(a.ctxOrBackground(), id)
+}
+
+func (a *App) SnippetsSave(snippet snippets.Snippet) (string, error) {
+	return a.snippets.Save(a.ctxOrBackground(), snippet)
+}
+
+func (a *App) SnippetsDelete(id string) error {
+	return a.snippetStore.Delete(a.ctxOrBackground(), id)
+}
+
+func (a *App) SnippetRender(id string, values map[string]string) (string, error) {
+	return a.snippets.Render(a.ctxOrBackground(), id, values)
+}
+
+// SnippetSend types a rendered snippet into a terminal, and into the rest of
+// its broadcast group if it has one.
+func (a *App) SnippetSend(id, termID string, values map[string]string, execute bool) error {
+	return a.snippets.Send(a.ctxOrBackground(), id, termID, values, execute)
+}
+
+// SnippetRun runs a rendered snippet through the exec service and returns the
+// job ID.
+func (a *App) SnippetRun(id string, values map[string]string, request batch.Request) (string, error) {
+	return a.snippets.Run(a.ctxOrBackground(), id, values, request)
+}
+
+// SnippetsExport writes the given snippets, or all when ids is empty, to path
+// as JSON or YAML depending on the extension.
+func (a *App) SnippetsExport(path string, ids []string) error {
+	data, err := snippets.Export(a.ctxOrBackground(), a.snippetStore, ids, snippets.FormatFromPath(path))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (a *App) SnippetsImport(path string, options snippets.ImportOptions) ([]snippets.ImportItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return snippets.Import(a.ctxOrBackground(), a.snippetStore, data, options)
+}
+
+func (a *App) BroadcastCreate(name string) (terminal.BroadcastGroup, error) {
+	return a.terminals.CreateBroadcast(name)
+}
+
+func (a *App) BroadcastDelete(groupID string) error {
+	return a.terminals.DeleteBroadcast(groupID)
+}
+
+func (a *App) BroadcastList() []terminal.BroadcastGroup {
+	return a.terminals.ListBroadcasts()
+}
+
+// BroadcastAdd may wait for a "broadcast:confirm" answer when the group would
+// span more than one profile group.
+func (a *App) BroadcastAdd(groupID, termID string) (terminal.BroadcastGroup, error) {
+	return a.terminals.AddToBroadcast(groupID, termID)
+}
+
+func (a *App) BroadcastRemove(groupID, termID string) (terminal.BroadcastGroup, error) {
+	return a.terminals.RemoveFromBroadcast(groupID, termID)
+}
+
+func (a *App) BroadcastSetEnabled(groupID, termID string, enabled bool) (terminal.BroadcastGroup, error) {
+	return a.terminals.SetBroadcastEnabled(groupID, termID, enabled)
+}
+
+func (a *App) BroadcastConfirmRespond(requestID string, allow bool) error {
+	return a.broadcasts.Resolve(requestID, allow)
+}
+
+// TriggersList returns the trigger rules of a profile; terminal.AllProfiles
+// ("*") holds the rules applied to every profile.
+func (a *App) TriggersList(profileID string) []terminal.TriggerRule {
+	return a.triggerRules.List(profileID)
+}
+
+// TriggersSet replaces the trigger rules of a profile and applies them to
+// open terminals.
+func (a *App) TriggersSet(profileID string, rules []terminal.TriggerRule) error {
+	if err := a.triggerRules.Set(profileID, rules); err != nil {
+		return err
+	}
+	a.terminals.ReloadTriggers()
+	return nil
+}
+
+func (a *App) TerminalMarks(termID string) ([]terminal.Mark, error) {
+	return a.terminals.Marks(termID)
+}
+
+func (a *App) RecordingStart(termID string, options terminal.RecordOptions) (terminal.Recording, error) {
+	return a.terminals.StartRecording(termID, options)
+}
+
+func (a *App) RecordingStop(termID string) (terminal.Recording, error) {
+	return a.terminals.StopRecording(termID)
+}
+
+// RecordingsList returns saved recordings, marking the ones still being
+// written.
+func (a *App) RecordingsList() ([]terminal.Recording, error) {
+	items, err := terminal.ListRecordings(a.recordDir)
+	if err != nil {
+		return nil, err
+	}
+	active := map[string]terminal.Recording{}
+	for _, rec := range a.terminals.ActiveRecordings() {
+		active[rec.ID] = rec
+	}
+	for i := range items {
+		if rec, ok := active[items[i].ID]; ok {
+			items[i].Active = true
+			items[i].Duration = rec.Duration
+		}
+	}
+	return items, nil
+}
+
+func (a *App) RecordingsDelete(id string) error {
+	for _, rec := range a.terminals.ActiveRecordings() {
+		if rec.ID == id {
+			return errors.New("recording is still in progress")
+		}
+	}
+	return terminal.DeleteRecording(a.recordDir, id)
+}
+
+// PlaybackOpen starts replaying a saved recording. Speed 0 plays in real time.
+func (a *App) PlaybackOpen(recordingID string, speed float64) (terminal.PlaybackState, error) {
+	path, err := terminal.RecordingPath(a.recordDir, recordingID)
+	if err != nil {
+		return terminal.PlaybackState{}, err
+	}
+	return a.player.Open(path, speed)
+}
+
+func (a *App) PlaybackPause(playbackID string) (terminal.PlaybackState, error) {
+	return a.player.Pause(playbackID)
+}
+
+func (a *App) PlaybackResume(playbackID string) (terminal.PlaybackState, error) {
+	return a.player.Resume(playbackID)
+}
+
+func (a *App) PlaybackSeek(playbackID string, seconds float64) (terminal.PlaybackState, error) {
+	return a.player.Seek(playbackID, seconds)
+}
+
+func (a *App) PlaybackSetSpeed(playbackID string, speed float64) (terminal.PlaybackState, error) {
+	return a.player.SetSpeed(playbackID, speed)
+}
+
+func (a *App) PlaybackClose(playbackID string) error {
+	return a.player.Close(playbackID)
+}
+
+func (a *App) RecordingText(recordingID string) ([]terminal.TextLine, error) {
+	path, err := terminal.RecordingPath(a.recordDir, recordingID)
+	if err != nil {
+		return nil, err
+	}
+	return terminal.RecordingText(path)
+}
+
+func (a *App) RecordingRulesList() []terminal.AutoRecordRule {
+	return a.recordRules.List()
+}
+
+func (a *App) RecordingRulesSet(rules []terminal.AutoRecordRule) error {
+	return a.recordRules.Set(rules)
+}
+
 func (a *App) FilesList(sessionID, path string) ([]sftp.FileEntry, error) {
 	return a.files.List(sessionID, path)
 }
@@ -246,10 +621,66 @@ func (a *App) TransferListTasks() []transfer.Task {
 	return a.transfers.ListTasks()
 }
 
+func (a *App) ForwardsStartLocal(sessionID, listenAddr, targetAddr string) (string, error) {
+	return a.forwards.StartLocal(sessionID, listenAddr, targetAddr)
+}
+
+func (a *App) ForwardsStartRemote(sessionID, listenAddr, targetAddr string) (string, error) {
+	return a.forwards.StartRemote(sessionID, listenAddr, targetAddr)
+}
+
+func (a *App) ForwardsStartDynamic(sessionID, listenAddr, username, password string) (string, error) {
+	return a.forwards.StartDynamic(sessionID, listenAddr, username, password)
+}
+
+func (a *App) ForwardsList() []forward.Forward {
+	return a.forwards.List()
+}
+
+func (a *App) ForwardsStop(forwardID string) error {
+	return a.forwards.Stop(forwardID)
+}
+
+func (a *App) ForwardsDestinations(forwardID string) ([]forward.DestinationStats, error) {
+	return a.forwards.Destinations(forwardID)
+}
+
 func (a *App) HostKeyRespond(requestID string, allow bool) error {
 	return a.prompts.Resolve(requestID, allow)
 }
 
+func (a *App) KnownHostsList(host string) ([]hostkey.Entry, error) {
+	return hostkey.ListEntries(a.hostKeyPath, host)
+}
+
+func (a *App) KnownHostsDelete(line int, fingerprint string) error {
+	return hostkey.DeleteEntry(a.hostKeyPath, line, fingerprint)
+}
+
+func (a *App) KnownHostsReplace(line int, fingerprint, publicKey string) error {
+	return hostkey.ReplaceEntry(a.hostKeyPath, line, fingerprint, publicKey)
+}
+
+func (a *App) HostCAList() ([]hostkey.Authority, error) {
+	return hostkey.ListAuthorities(a.hostKeyPath)
+}
+
+func (a *App) HostCAAdd(pattern, publicKey string) error {
+	return hostkey.AddAuthority(a.hostKeyPath, pattern, publicKey)
+}
+
+func (a *App) HostCARemove(pattern, fingerprint string) error {
+	return hostkey.RemoveAuthority(a.hostKeyPath, pattern, fingerprint)
+}
+
+func (a *App) AuthPromptRespond(requestID string, answers []string) error {
+	return a.authPrompts.Resolve(requestID, answers)
+}
+
+func (a *App) AuthPromptCancel(requestID string) error {
+	return a.authPrompts.Cancel(requestID)
+}
+
 func (a *App) CredentialsSetPassword(profileID, password string) error {
 	return keyring.SetPassword(profileID, password)
 }
@@ -265,6 +696,152 @@ func (a *App) CredentialsDelete(profileID string) error {
 	return keyring.DeletePrivateKeyPassphrase(profileID)
 }
 
+func (a *App) VaultStatus() keyring.VaultStatus {
+	return a.vault.Status()
+}
+
+func (a *App) VaultUnlock(masterPassword string) error {
+	if err := a.vault.Unlock(masterPassword); err != nil {
+		return err
+	}
+	a.emitter.Emit("vault:state", a.vault.Status())
+	return nil
+}
+
+func (a *App) VaultLock() {
+	a.vault.Lock()
+}
+
+func (a *App) VaultSetIdleTimeout(seconds int) {
+	a.vault.SetIdleTimeout(time.Duration(seconds) * time.Second)
+}
+
+// SecretsMigrate moves every profile secret from the active backend to target
+// ("system" or "vault") and makes target the active backend. An emptied vault
+// file is removed so the next start picks the system store again.
+func (a *App) SecretsMigrate(target string) (int, error) {
+	from := keyring.ActiveBackend()
+	var to keyring.Backend
+	switch target {
+	case keyring.BackendSystem:
+		if !keyring.SystemAvailable() {
+			return 0, errors.New("system keyring is not available")
+		}
+		to = keyring.System{}
+	case keyring.BackendVault:
+		to = a.vault
+	default:
+		return 0, fmt.Errorf("unknown secret backend: %s", target)
+	}
+	if from.Name() == to.Name() {
+		return 0, nil
+	}
+
+	keys, err := a.secretKeys()
+	if err != nil {
+		return 0, err
+	}
+	moved, err := keyring.Migrate(from, to, keys)
+	if err != nil {
+		return moved, err
+	}
+	keyring.SetBackend(to)
+
+	if from.Name() == keyring.BackendVault {
+		if remaining, err := a.vault.Keys(); err == nil && len(remaining) == 0 {
+			if err := a.vault.Remove(); err != nil {
+				return moved, err
+			}
+		}
+	}
+	a.emitter.Emit("vault:state", a.vault.Status())
+	return moved, nil
+}
+
+// secretKeys lists the keyring keys of every stored profile plus whatever the
+// vault holds, so orphaned vault entries move too.
+func (a *App) secretKeys() ([]string, error) {
+	ctx := a.ctxOrBackground()
+	sshProfiles, err := a.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mysqlProfiles, err := a.mysqlStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profileIDs := make([]string, 0, len(sshProfiles))
+	for _, profile := range sshProfiles {
+		profileIDs = append(profileIDs, profile.ID)
+	}
+	mysqlIDs := make([]string, 0, len(mysqlProfiles))
+	for _, profile := range mysqlProfiles {
+		mysqlIDs = append(mysqlIDs, profile.ID)
+	}
+
+	keys := keyring.SecretKeys(profileIDs, mysqlIDs)
+	seen := map[string]bool{}
+	for _, key := range keys {
+		seen[key] = true
+	}
+	vaultKeys, err := a.vault.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range vaultKeys {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (a *App) KeysGenerate(options sshkeys.GenerateOptions) (sshkeys.Key, error) {
+	return a.keys.Generate(options)
+}
+
+func (a *App) KeysConvert(srcPath, passphrase, name, comment string) (sshkeys.Key, error) {
+	return a.keys.Convert(srcPath, passphrase, name, comment)
+}
+
+func (a *App) KeysList() ([]sshkeys.Key, error) {
+	return a.keys.List()
+}
+
+func (a *App) KeysDelete(name string) error {
+	return a.keys.Delete(name)
+}
+
+func (a *App) KeysExportPublic(name, destPath string) error {
+	return a.keys.ExportPublicKey(name, destPath)
+}
+
+// KeysDeploy installs the public half of a key on the host of a connected
+// session and checks that the key can log in.
+func (a *App) KeysDeploy(sessionID string, options sshkeys.DeployOptions) (sshkeys.DeployResult, error) {
+	return a.deployer.Deploy(a.ctxOrBackground(), sessionID, options)
+}
+
+func (a *App) AgentKeys() ([]sshagent.Key, error) {
+	return a.agent.Keys()
+}
+
+func (a *App) AgentLoadProfileKey(profileID string) error {
+	profile, err := a.store.Get(a.ctxOrBackground(), profileID)
+	if err != nil {
+		return err
+	}
+	if profile.PrivateKeyPath == "" {
+		return errors.New("profile has no private key")
+	}
+	return a.agent.LoadKey(profile.ID, profile.PrivateKeyPath, profile.Name)
+}
+
+func (a *App) AgentRemoveAll() error {
+	return a.agent.RemoveAll()
+}
+
 func (a *App) DialogOpenFile(title string) (string, error) {
 	return runtime.OpenFileDialog(a.ctxOrBackground(), runtime.OpenDialogOptions{
 		Title: title,
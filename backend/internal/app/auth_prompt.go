@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"goterm/backend/internal/common"
+)
+
+var errAuthPromptCanceled = errors.New("authentication canceled")
+
+type AuthQuestion struct {
+	Prompt string `json:"prompt"`
+	Echo   bool   `json:"echo"`
+}
+
+type AuthPrompt struct {
+	ID          string         `json:"id"`
+	ProfileID   string         `json:"profileId"`
+	Host        string         `json:"host"`
+	User        string         `json:"user"`
+	Name        string         `json:"name"`
+	Instruction string         `json:"instruction"`
+	Questions   []AuthQuestion `json:"questions"`
+}
+
+type authAnswer struct {
+	answers  []string
+	canceled bool
+}
+
+// AuthPromptManager relays keyboard-interactive challenges to the UI and waits
+// for the answers.
+type AuthPromptManager struct {
+	emitter common.Emitter
+
+	mu      sync.Mutex
+	pending map[string]chan authAnswer
+}
+
+func NewAuthPromptManager(emitter common.Emitter) *AuthPromptManager {
+	if emitter == nil {
+		emitter = common.NopEmitter{}
+	}
+	return &AuthPromptManager{
+		emitter: emitter,
+		pending: map[string]chan authAnswer{},
+	}
+}
+
+func (m *AuthPromptManager) Ask(profileID, host, user, name, instruction string, questions []string, echos []bool) ([]string, error) {
+	id, err := common.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan authAnswer, 1)
+
+	m.mu.Lock()
+	m.pending[id] = ch
+	m.mu.Unlock()
+
+	prompt := AuthPrompt{
+		ID:          id,
+		ProfileID:   profileID,
+		Host:        host,
+		User:        user,
+		Name:        name,
+		Instruction: instruction,
+		Questions:   make([]AuthQuestion, len(questions)),
+	}
+	for i, question := range questions {
+		prompt.Questions[i] = AuthQuestion{Prompt: question, Echo: i < len(echos) && echos[i]}
+	}
+	m.emitter.Emit("auth:prompt", prompt)
+
+	select {
+	case answer := <-ch:
+		if answer.canceled {
+			return nil, errAuthPromptCanceled
+		}
+		return answer.answers, nil
+	case <-time.After(2 * time.Minute):
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, errors.New("authentication prompt timeout")
+	}
+}
+
+func (m *AuthPromptManager) Resolve(id string, answers []string) error {
+	return m.finish(id, authAnswer{answers: answers})
+}
+
+func (m *AuthPromptManager) Cancel(id string) error {
+	return m.finish(id, authAnswer{canceled: true})
+}
+
+func (m *AuthPromptManager) finish(id string, answer authAnswer) error {
+	m.mu.Lock()
+	ch, ok := m.pending[id]
+	if ok {
+		delete(m.pending, id)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return common.ErrNotFound
+	}
+
+	ch <- answer
+	close(ch)
+	return nil
+}
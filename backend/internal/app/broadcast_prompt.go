@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"goterm/backend/internal/common"
+	"goterm/backend/internal/profiles"
+	"goterm/backend/internal/terminal"
+)
+
+// BroadcastHost is one host a broadcast would reach.
+type BroadcastHost struct {
+	ProfileID string `json:"profileId"`
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Group     string `json:"group"`
+}
+
+// BroadcastPrompt asks the user to confirm a broadcast group that reaches
+// hosts from more than one profile group, e.g. staging and production.
+type BroadcastPrompt struct {
+	ID            string          `json:"id"`
+	Broadcast     string          `json:"broadcast"`
+	ProfileGroups []string        `json:"profileGroups"`
+	Hosts         []BroadcastHost `json:"hosts"`
+}
+
+type BroadcastPromptManager struct {
+	emitter common.Emitter
+
+	mu      sync.Mutex
+	pending map[string]chan bool
+}
+
+func NewBroadcastPromptManager(emitter common.Emitter) *BroadcastPromptManager {
+	if emitter == nil {
+		emitter = common.NopEmitter{}
+	}
+	return &BroadcastPromptManager{
+		emitter: emitter,
+		pending: map[string]chan bool{},
+	}
+}
+
+// Guard lets broadcast groups within a single profile group through and asks
+// about the rest.
+func (m *BroadcastPromptManager) Guard(sessionProfile func(sessionID string) (string, error), store profiles.Store) terminal.BroadcastGuardFunc {
+	return func(name string, sessionIDs []string) (bool, error) {
+		hosts := []BroadcastHost{}
+		groups := map[string]bool{}
+		seen := map[string]bool{}
+		for _, sessionID := range sessionIDs {
+			profileID, err := sessionProfile(sessionID)
+			if err != nil {
+				return false, err
+			}
+			if seen[profileID] {
+				continue
+			}
+			seen[profileID] = true
+			profile, err := store.Get(context.Background(), profileID)
+			if err != nil {
+				return false, err
+			}
+			groups[profile.Group] = true
+			hosts = append(hosts, BroadcastHost{
+				ProfileID: profile.ID,
+				Name:      profile.Name,
+				Host:      profile.Host,
+				Group:     profile.Group,
+			})
+		}
+		if len(groups) <= 1 {
+			return true, nil
+		}
+
+		profileGroups := make([]string, 0, len(groups))
+		for group := range groups {
+			profileGroups = append(profileGroups, group)
+		}
+		sort.Strings(profileGroups)
+		return m.Ask(name, profileGroups, hosts)
+	}
+}
+
+func (m *BroadcastPromptManager) Ask(name string, profileGroups []string, hosts []BroadcastHost) (bool, error) {
+	id, err := common.NewID()
+	if err != nil {
+		return false, err
+	}
+
+	ch := make(chan bool, 1)
+
+	m.mu.Lock()
+	m.pending[id] = ch
+	m.mu.Unlock()
+
+	m.emitter.Emit("broadcast:confirm", BroadcastPrompt{
+		ID:            id,
+		Broadcast:     name,
+		ProfileGroups: profileGroups,
+		Hosts:         hosts,
+	})
+
+	select {
+	case allow := <-ch:
+		return allow, nil
+	case <-time.After(2 * time.Minute):
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return false, errors.New("broadcast confirmation timeout")
+	}
+}
+
+func (m *BroadcastPromptManager) Resolve(id string, allow bool) error {
+	m.mu.Lock()
+	ch, ok := m.pending[id]
+	if ok {
+		delete(m.pending, id)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return common.ErrNotFound
+	}
+
+	ch <- allow
+	close(ch)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"goterm/backend/internal/common"
 	"goterm/backend/internal/mysql"
 	"goterm/backend/internal/profiles"
+	"goterm/backend/internal/snippets"
 )
 
 type Config struct {
@@ -14,6 +15,7 @@ type Config struct {
 	Emitter      common.Emitter
 	ProfileStore profiles.Store
 	MySQLStore   mysql.Store
+	SnippetStore snippets.Store
 	HostKeyPath  string
 }
 
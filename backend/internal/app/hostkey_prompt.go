@@ -16,6 +16,15 @@ type HostKeyPrompt struct {
     Fingerprint string `json:"fingerprint"`
 }
 
+// HostKeyChangedPrompt is raised when a host presents a key that differs from
+// the recorded ones. Allowing it replaces the known_hosts entries.
+type HostKeyChangedPrompt struct {
+    ID                   string   `json:"id"`
+    Host                 string   `json:"host"`
+    Fingerprint          string   `json:"fingerprint"`
+    PreviousFingerprints []string `json:"previousFingerprints"`
+}
+
 type HostKeyPromptManager struct {
     emitter common.Emitter
 
@@ -34,6 +43,27 @@ func NewHostKeyPromptManager(emitter common.Emitter) *HostKeyPromptManager {
 }
 
 func (m *HostKeyPromptManager) Ask(host string, _ ssh.PublicKey, fingerprint string) (bool, error) {
+    return m.wait("hostkey:prompt", func(id string) any {
+        return HostKeyPrompt{
+            ID:          id,
+            Host:        host,
+            Fingerprint: fingerprint,
+        }
+    })
+}
+
+func (m *HostKeyPromptManager) AskChanged(host string, _ ssh.PublicKey, fingerprint string, previous []string) (bool, error) {
+    return m.wait("hostkey:changed", func(id string) any {
+        return HostKeyChangedPrompt{
+            ID:                   id,
+            Host:                 host,
+            Fingerprint:          fingerprint,
+            PreviousFingerprints: previous,
+        }
+    })
+}
+
+func (m *HostKeyPromptManager) wait(event string, payload func(id string) any) (bool, error) {
     id, err := common.NewID()
     if err != nil {
         return false, err
@@ -45,11 +75,7 @@ func (m *HostKeyPromptManager) Ask(host string, _ ssh.PublicKey, fingerprint str
     m.pending[id] = ch
     m.mu.Unlock()
 
-    m.emitter.Emit("hostkey:prompt", HostKeyPrompt{
-        ID:          id,
-        Host:        host,
-        Fingerprint: fingerprint,
-    })
+    m.emitter.Emit(event, payload(id))
 
     select {
     case allowed := <-ch:
@@ -0,0 +1,457 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/common"
+	"goterm/backend/internal/profiles"
+)
+
+const (
+	defaultConcurrency = 8
+	maxConcurrency     = 64
+	defaultTimeout     = 5 * time.Minute
+	// outputLimit caps what is kept per host and stream for the summary.
+	// Everything is still streamed as events.
+	outputLimit = 64 * 1024
+)
+
+const (
+	StatusQueued     = "queued"
+	StatusConnecting = "connecting"
+	StatusRunning    = "running"
+	StatusSucceeded  = "succeeded"
+	StatusFailed     = "failed"
+	StatusTimeout    = "timeout"
+	StatusCanceled   = "canceled"
+)
+
+// Sessions is the part of the session manager a job needs.
+type Sessions interface {
+	Connect(ctx context.Context, profileID string) (string, error)
+	Disconnect(sessionID string) error
+	GetClient(sessionID string) (*ssh.Client, error)
+	SessionFor(profileID string) (string, bool)
+}
+
+type Request struct {
+	ProfileIDs  []string `json:"profileIds"`
+	Command     string   `json:"command"`
+	Concurrency int      `json:"concurrency"`
+	// TimeoutSeconds bounds connecting and running on each host.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// KeepConnected leaves sessions opened for the job connected afterwards.
+	// Sessions that were already open are never closed.
+	KeepConnected bool `json:"keepConnected"`
+}
+
+// HostResult is one row of the summary table.
+type HostResult struct {
+	ProfileID       string `json:"profileId"`
+	Name            string `json:"name"`
+	Host            string `json:"host"`
+	Status          string `json:"status"`
+	ExitCode        int    `json:"exitCode"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	OutputTruncated bool   `json:"outputTruncated"`
+	Error           string `json:"error,omitempty"`
+	StartedAt       int64  `json:"startedAt"`
+	DurationMs      int64  `json:"durationMs"`
+}
+
+type Summary struct {
+	JobID      string       `json:"jobId"`
+	Command    string       `json:"command"`
+	StartedAt  int64        `json:"startedAt"`
+	FinishedAt int64        `json:"finishedAt"`
+	Done       bool         `json:"done"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Hosts      []HostResult `json:"hosts"`
+}
+
+// OutputEvent is emitted as "exec:output" for every chunk a host writes.
+type OutputEvent struct {
+	JobID     string `json:"jobId"`
+	ProfileID string `json:"profileId"`
+	Stream    string `json:"stream"`
+	Chunk     string `json:"chunk"`
+}
+
+// HostEvent is emitted as "exec:host" whenever a host changes status.
+type HostEvent struct {
+	JobID  string     `json:"jobId"`
+	Result HostResult `json:"result"`
+}
+
+// Service runs one command on many hosts at once.
+type Service struct {
+	sessions Sessions
+	store    profiles.Store
+	emitter  common.Emitter
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+type job struct {
+	id      string
+	command string
+	started time.Time
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	hosts    []HostResult
+	starts   []time.Time
+	finished time.Time
+}
+
+func NewService(sessions Sessions, store profiles.Store, emitter common.Emitter) *Service {
+	if emitter == nil {
+		emitter = common.NopEmitter{}
+	}
+	return &Service{
+		sessions: sessions,
+		store:    store,
+		emitter:  emitter,
+		jobs:     map[string]*job{},
+	}
+}
+
+// Start runs the command on every profile in the background and returns the
+// job ID. Progress arrives as "exec:host" and "exec:output" events and the
+// final table as "exec:done".
+func (s *Service) Start(ctx context.Context, req Request) (string, error) {
+	if req.Command == "" {
+		return "", errors.New("command is required")
+	}
+	if len(req.ProfileIDs) == 0 {
+		return "", errors.New("no profiles selected")
+	}
+
+	hosts := make([]HostResult, 0, len(req.ProfileIDs))
+	seen := map[string]bool{}
+	for _, profileID := range req.ProfileIDs {
+		if seen[profileID] {
+			continue
+		}
+		seen[profileID] = true
+		profile, err := s.store.Get(ctx, profileID)
+		if err != nil {
+			return "", err
+		}
+		hosts = append(hosts, HostResult{
+			ProfileID: profile.ID,
+			Name:      profile.Name,
+			Host:      profile.Host,
+			Status:    StatusQueued,
+			ExitCode:  -1,
+		})
+	}
+
+	id, err := common.NewID()
+	if err != nil {
+		return "", err
+	}
+	jobCtx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:      id,
+		command: req.Command,
+		started: time.Now(),
+		cancel:  cancel,
+		hosts:   hosts,
+		starts:  make([]time.Time, len(hosts)),
+	}
+
+	s.mu.Lock()
+	s.jobs[id] = j
+	s.mu.Unlock()
+
+	go s.run(jobCtx, j, req)
+	return id, nil
+}
+
+func (s *Service) Cancel(jobID string) error {
+	j, err := s.get(jobID)
+	if err != nil {
+		return err
+	}
+	j.cancel()
+	return nil
+}
+
+func (s *Service) Summary(jobID string) (Summary, error) {
+	j, err := s.get(jobID)
+	if err != nil {
+		return Summary{}, err
+	}
+	return j.summary(), nil
+}
+
+// Forget drops a finished job and its collected output.
+func (s *Service) Forget(jobID string) error {
+	j, err := s.get(jobID)
+	if err != nil {
+		return err
+	}
+	if !j.summary().Done {
+		return errors.New("job is still running")
+	}
+	s.mu.Lock()
+	delete(s.jobs, jobID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Service) get(jobID string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	return j, nil
+}
+
+func (s *Service) run(ctx context.Context, j *job, req Request) {
+	defer j.cancel()
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	if concurrency > maxConcurrency {
+		concurrency = maxConcurrency
+	}
+	timeout := defaultTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range j.hosts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				s.finish(j, i, StatusCanceled, -1, ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+			s.runHost(ctx, j, i, timeout, req.KeepConnected)
+		}(i)
+	}
+	wg.Wait()
+
+	j.mu.Lock()
+	j.finished = time.Now()
+	j.mu.Unlock()
+	s.emitter.Emit("exec:done", j.summary())
+}
+
+func (s *Service) runHost(ctx context.Context, j *job, i int, timeout time.Duration, keepConnected bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	profileID := s.update(j, i, func(result *HostResult) {
+		j.starts[i] = time.Now()
+		result.Status = StatusConnecting
+		result.StartedAt = j.starts[i].Unix()
+	})
+
+	_, existed := s.sessions.SessionFor(profileID)
+	sessionID, err := s.connect(ctx, profileID, existed)
+	if err != nil {
+		s.finish(j, i, statusOf(ctx, StatusFailed), -1, err)
+		return
+	}
+	if !existed && !keepConnected {
+		defer s.sessions.Disconnect(sessionID)
+	}
+
+	client, err := s.sessions.GetClient(sessionID)
+	if err != nil {
+		s.finish(j, i, StatusFailed, -1, err)
+		return
+	}
+	sshSession, err := client.NewSession()
+	if err != nil {
+		s.finish(j, i, StatusFailed, -1, err)
+		return
+	}
+	defer sshSession.Close()
+
+	stdout := &stream{service: s, job: j, index: i, profileID: profileID, name: "stdout"}
+	stderr := &stream{service: s, job: j, index: i, profileID: profileID, name: "stderr"}
+	sshSession.Stdout = stdout
+	sshSession.Stderr = stderr
+
+	s.update(j, i, func(result *HostResult) {
+		result.Status = StatusRunning
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- sshSession.Run(j.command) }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = sshSession.Signal(ssh.SIGKILL)
+		_ = sshSession.Close()
+		<-done
+		s.finish(j, i, statusOf(ctx, StatusFailed), -1, ctx.Err())
+		return
+	}
+
+	if err == nil {
+		s.finish(j, i, StatusSucceeded, 0, nil)
+		return
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		s.finish(j, i, StatusFailed, exitErr.ExitStatus(), nil)
+		return
+	}
+	s.finish(j, i, StatusFailed, -1, err)
+}
+
+type connectResult struct {
+	sessionID string
+	err       error
+}
+
+// connect returns when the session is up or ctx ends, whichever is first.
+// Dialing and host key or login prompts do not observe ctx, so a connect
+// that outlives it is left to finish in the background and a session it
+// opened is closed.
+func (s *Service) connect(ctx context.Context, profileID string, existed bool) (string, error) {
+	done := make(chan connectResult, 1)
+	go func() {
+		sessionID, err := s.sessions.Connect(ctx, profileID)
+		done <- connectResult{sessionID: sessionID, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.sessionID, result.err
+	case <-ctx.Done():
+		go func() {
+			if result := <-done; result.err == nil && !existed {
+				_ = s.sessions.Disconnect(result.sessionID)
+			}
+		}()
+		return "", ctx.Err()
+	}
+}
+
+func (s *Service) finish(j *job, i int, status string, code int, err error) {
+	s.update(j, i, func(result *HostResult) {
+		result.Status = status
+		result.ExitCode = code
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if !j.starts[i].IsZero() {
+			result.DurationMs = time.Since(j.starts[i]).Milliseconds()
+		}
+	})
+}
+
+// update changes one host's row, emits it and returns its profile ID.
+func (s *Service) update(j *job, i int, fn func(*HostResult)) string {
+	j.mu.Lock()
+	fn(&j.hosts[i])
+	result := j.hosts[i]
+	j.mu.Unlock()
+
+	s.emitter.Emit("exec:host", HostEvent{JobID: j.id, Result: result})
+	return result.ProfileID
+}
+
+func (j *job) summary() Summary {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	summary := Summary{
+		JobID:     j.id,
+		Command:   j.command,
+		StartedAt: j.started.Unix(),
+		Done:      !j.finished.IsZero(),
+		Hosts:     append([]HostResult{}, j.hosts...),
+	}
+	if summary.Done {
+		summary.FinishedAt = j.finished.Unix()
+	}
+	for _, host := range j.hosts {
+		switch host.Status {
+		case StatusSucceeded:
+			summary.Succeeded++
+		case StatusFailed, StatusTimeout, StatusCanceled:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// stream forwards a host's output as events and keeps the start of it for
+// the summary.
+type stream struct {
+	service   *Service
+	job       *job
+	index     int
+	profileID string
+	name      string
+	// full is set once the kept output reached outputLimit.
+	full bool
+}
+
+func (w *stream) Write(p []byte) (int, error) {
+	w.job.mu.Lock()
+	result := &w.job.hosts[w.index]
+	target := &result.Stdout
+	if w.name == "stderr" {
+		target = &result.Stderr
+	}
+	if room := outputLimit - len(*target); room > 0 && !w.full {
+		cut := min(room, len(p))
+		if cut < len(p) {
+			// Stop at a character boundary so exports stay valid UTF-8.
+			for cut > 0 && !utf8.RuneStart(p[cut]) {
+				cut--
+			}
+			w.full = true
+			result.OutputTruncated = true
+		}
+		*target += string(p[:cut])
+	} else {
+		result.OutputTruncated = true
+	}
+	w.job.mu.Unlock()
+
+	w.service.emitter.Emit("exec:output", OutputEvent{
+		JobID:     w.job.id,
+		ProfileID: w.profileID,
+		Stream:    w.name,
+		Chunk:     string(p),
+	})
+	return len(p), nil
+}
+
+func statusOf(ctx context.Context, fallback string) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return StatusTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		return StatusCanceled
+	}
+	return fallback
+}
@@ -0,0 +1,90 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// stalledSessions never finishes a connect until release is closed, like a
+// host whose login prompt nobody answers.
+type stalledSessions struct {
+	release      chan struct{}
+	disconnected chan string
+}
+
+func (s *stalledSessions) Connect(context.Context, string) (string, error) {
+	<-s.release
+	return "late", nil
+}
+
+func (s *stalledSessions) Disconnect(sessionID string) error {
+	s.disconnected <- sessionID
+	return nil
+}
+
+func (s *stalledSessions) GetClient(string) (*ssh.Client, error) {
+	return nil, errors.New("not connected")
+}
+
+func (s *stalledSessions) SessionFor(string) (string, bool) {
+	return "", false
+}
+
+func TestConnectGivesUpWhenContextEnds(t *testing.T) {
+	sessions := &stalledSessions{release: make(chan struct{}), disconnected: make(chan string, 1)}
+	s := NewService(sessions, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := s.connect(ctx, "web", false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("connect error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("connect returned after %s", elapsed)
+	}
+
+	// The connect finishing later must not leave its session open.
+	close(sessions.release)
+	select {
+	case id := <-sessions.disconnected:
+		if id != "late" {
+			t.Fatalf("disconnected %q, want late", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late session was not disconnected")
+	}
+}
+
+func TestStreamCutsAtCharacterBoundary(t *testing.T) {
+	s := NewService(nil, nil, nil)
+	j := &job{id: "job", hosts: make([]HostResult, 1), starts: make([]time.Time, 1)}
+	w := &stream{service: s, job: j, profileID: "web", name: "stdout"}
+
+	// Three-byte characters do not divide outputLimit, so the limit falls
+	// inside one of them.
+	chunk := []byte(strings.Repeat("世", 1000))
+	for written := 0; written <= outputLimit; written += len(chunk) {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := j.hosts[0]
+	if !result.OutputTruncated {
+		t.Fatal("output not marked truncated")
+	}
+	if !utf8.ValidString(result.Stdout) {
+		t.Fatal("kept output is not valid UTF-8")
+	}
+	if len(result.Stdout) > outputLimit || len(result.Stdout) < outputLimit-utf8.UTFMax {
+		t.Fatalf("kept %d bytes, want just under %d", len(result.Stdout), outputLimit)
+	}
+}
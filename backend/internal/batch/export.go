@@ -0,0 +1,68 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// FormatFromPath picks the export format from a file extension.
+func FormatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSON
+}
+
+// Export renders the job's summary table as JSON or CSV.
+func (s *Service) Export(jobID, format string) ([]byte, error) {
+	summary, err := s.Summary(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "", FormatJSON:
+		return json.MarshalIndent(summary, "", "  ")
+	case FormatCSV:
+		return exportCSV(summary)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func exportCSV(summary Summary) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{
+		"profile_id", "name", "host", "status", "exit_code",
+		"duration_ms", "error", "stdout", "stderr", "output_truncated",
+	}}
+	for _, host := range summary.Hosts {
+		rows = append(rows, []string{
+			host.ProfileID,
+			host.Name,
+			host.Host,
+			host.Status,
+			strconv.Itoa(host.ExitCode),
+			strconv.FormatInt(host.DurationMs, 10),
+			host.Error,
+			host.Stdout,
+			host.Stderr,
+			strconv.FormatBool(host.OutputTruncated),
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
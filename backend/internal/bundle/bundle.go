@@ -0,0 +1,267 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/yaml.v3"
+
+	"goterm/backend/internal/mysql"
+	"goterm/backend/internal/profiles"
+	"goterm/backend/internal/security/hostkey"
+	"goterm/backend/internal/security/keyring"
+	"goterm/backend/internal/security/secretbox"
+)
+
+// Version is the bundle format version written by Export. Import accepts
+// bundles up to this version.
+const Version = 1
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Bundle is the portable form of a host inventory. Secrets are only ever
+// present encrypted.
+type Bundle struct {
+	Version       int                `json:"version"`
+	CreatedAt     int64              `json:"createdAt"`
+	Groups        []string           `json:"groups,omitempty"`
+	Profiles      []profiles.Profile `json:"profiles"`
+	MySQLProfiles []mysql.Profile    `json:"mysqlProfiles"`
+	KnownHosts    []string           `json:"knownHosts,omitempty"`
+	Secrets       *secretbox.Sealed  `json:"secrets,omitempty"`
+}
+
+// secrets is the plaintext sealed into Bundle.Secrets, keyed by profile ID.
+type secrets struct {
+	Passwords      map[string]string `json:"passwords,omitempty"`
+	Passphrases    map[string]string `json:"passphrases,omitempty"`
+	MySQLPasswords map[string]string `json:"mysqlPasswords,omitempty"`
+}
+
+// ExportOptions selects what goes into a bundle. Profiles are picked by ID
+// or by group; with no selection at all every profile is exported. Jump hosts
+// and SSH tunnels of selected profiles are always included.
+type ExportOptions struct {
+	ProfileIDs        []string `json:"profileIds"`
+	MySQLProfileIDs   []string `json:"mysqlProfileIds"`
+	Groups            []string `json:"groups"`
+	IncludeKnownHosts bool     `json:"includeKnownHosts"`
+	// Passphrase, when set, embeds the keyring secrets of the exported
+	// profiles encrypted with it.
+	Passphrase string `json:"passphrase"`
+	Format     string `json:"format"`
+}
+
+type Service struct {
+	profiles       profiles.Store
+	mysql          mysql.Store
+	knownHostsPath string
+}
+
+func NewService(profileStore profiles.Store, mysqlStore mysql.Store, knownHostsPath string) *Service {
+	return &Service{
+		profiles:       profileStore,
+		mysql:          mysqlStore,
+		knownHostsPath: knownHostsPath,
+	}
+}
+
+// FormatFromPath picks the bundle format from a file extension.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+func (s *Service) Export(ctx context.Context, opts ExportOptions) ([]byte, error) {
+	allProfiles, err := s.profiles.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allMySQL, err := s.mysql.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selectAll := len(opts.ProfileIDs) == 0 && len(opts.MySQLProfileIDs) == 0 && len(opts.Groups) == 0
+
+	byID := map[string]profiles.Profile{}
+	for _, profile := range allProfiles {
+		byID[profile.ID] = profile
+	}
+	wanted := map[string]bool{}
+	for _, id := range opts.ProfileIDs {
+		wanted[id] = true
+	}
+	groups := map[string]bool{}
+	for _, group := range opts.Groups {
+		groups[group] = true
+	}
+	wantedMySQL := map[string]bool{}
+	for _, id := range opts.MySQLProfileIDs {
+		wantedMySQL[id] = true
+	}
+
+	b := &Bundle{
+		Version:       Version,
+		CreatedAt:     time.Now().Unix(),
+		Profiles:      []profiles.Profile{},
+		MySQLProfiles: []mysql.Profile{},
+	}
+
+	for _, profile := range allMySQL {
+		if selectAll || wantedMySQL[profile.ID] {
+			b.MySQLProfiles = append(b.MySQLProfiles, profile)
+			if profile.SSHProfileID != "" {
+				wanted[profile.SSHProfileID] = true
+			}
+		}
+	}
+
+	for _, profile := range allProfiles {
+		if selectAll || wanted[profile.ID] || (profile.Group != "" && groups[profile.Group]) {
+			includeWithJumps(profile.ID, byID, wanted)
+		}
+	}
+	groupSet := map[string]bool{}
+	for _, profile := range allProfiles {
+		if !wanted[profile.ID] {
+			continue
+		}
+		b.Profiles = append(b.Profiles, profile)
+		if profile.Group != "" && !groupSet[profile.Group] {
+			groupSet[profile.Group] = true
+			b.Groups = append(b.Groups, profile.Group)
+		}
+	}
+	sort.Strings(b.Groups)
+
+	if opts.IncludeKnownHosts && len(b.Profiles) > 0 {
+		hosts := make([]string, 0, len(b.Profiles))
+		for _, profile := range b.Profiles {
+			port := profile.Port
+			if port == 0 {
+				port = 22
+			}
+			// Normalize gives the form entries are stored in: a bare host
+			// for port 22, "[host]:port" otherwise, IPv6 included.
+			hosts = append(hosts, knownhosts.Normalize(net.JoinHostPort(profile.Host, strconv.Itoa(port))))
+		}
+		b.KnownHosts, err = hostkey.HostLines(s.knownHostsPath, hosts)
+		if err != nil {
+			return nil, fmt.Errorf("read known_hosts: %w", err)
+		}
+	}
+
+	if opts.Passphrase != "" {
+		plain, err := json.Marshal(collectSecrets(b))
+		if err != nil {
+			return nil, err
+		}
+		b.Secrets, err = secretbox.Seal(opts.Passphrase, plain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encode(b, opts.Format)
+}
+
+// includeWithJumps marks id and, transitively, its jump hosts as wanted.
+func includeWithJumps(id string, byID map[string]profiles.Profile, wanted map[string]bool) {
+	profile, ok := byID[id]
+	if !ok {
+		return
+	}
+	wanted[id] = true
+	for _, jumpID := range profile.JumpProfileIDs {
+		if !wanted[jumpID] {
+			includeWithJumps(jumpID, byID, wanted)
+		}
+	}
+}
+
+func collectSecrets(b *Bundle) secrets {
+	out := secrets{
+		Passwords:      map[string]string{},
+		Passphrases:    map[string]string{},
+		MySQLPasswords: map[string]string{},
+	}
+	for _, profile := range b.Profiles {
+		if password, err := keyring.GetPassword(profile.ID); err == nil {
+			out.Passwords[profile.ID] = password
+		}
+		if passphrase, err := keyring.GetPrivateKeyPassphrase(profile.ID); err == nil {
+			out.Passphrases[profile.ID] = passphrase
+		}
+	}
+	for _, profile := range b.MySQLProfiles {
+		if password, err := keyring.GetMySQLPassword(profile.ID); err == nil {
+			out.MySQLPasswords[profile.ID] = password
+		}
+	}
+	return out
+}
+
+// encode writes b as indented JSON or as YAML. YAML goes through the JSON
+// form so both formats share the json field names.
+func encode(b *Bundle, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "", FormatJSON:
+		return data, nil
+	case FormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+}
+
+func decode(data []byte) (*Bundle, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if trimmed[0] != '{' {
+		var doc any
+		if err := yaml.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("parse bundle: %w", err)
+		}
+		var err error
+		if trimmed, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("parse bundle: %w", err)
+		}
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(trimmed, &b); err != nil {
+		return nil, fmt.Errorf("parse bundle: %w", err)
+	}
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return &b, nil
+}
@@ -0,0 +1,86 @@
+package bundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"goterm/backend/internal/mysql"
+	"goterm/backend/internal/profiles"
+)
+
+type profileList struct {
+	profiles.Store
+	items []profiles.Profile
+}
+
+func (s profileList) List(context.Context) ([]profiles.Profile, error) {
+	return s.items, nil
+}
+
+type mysqlList struct {
+	mysql.Store
+}
+
+func (mysqlList) List(context.Context) ([]mysql.Profile, error) {
+	return nil, nil
+}
+
+func hostKeyLine(t *testing.T, host string) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return knownhosts.Line([]string{host}, key)
+}
+
+func TestExportKnownHostsForProfilePorts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	custom := hostKeyLine(t, "[web.example.com]:2222")
+	standard := hostKeyLine(t, "db.example.com")
+	ipv6 := hostKeyLine(t, "[2001:db8::1]:2200")
+	other := hostKeyLine(t, "[web.example.com]:2200")
+	data := strings.Join([]string{custom, standard, ipv6, other}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := profileList{items: []profiles.Profile{
+		{ID: "web", Name: "web", Host: "web.example.com", Port: 2222},
+		{ID: "db", Name: "db", Host: "db.example.com", Port: 0},
+		{ID: "v6", Name: "v6", Host: "2001:db8::1", Port: 2200},
+	}}
+	service := NewService(store, mysqlList{}, path)
+
+	out, err := service.Export(context.Background(), ExportOptions{IncludeKnownHosts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(out, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{custom, standard, ipv6}
+	if len(b.KnownHosts) != len(want) {
+		t.Fatalf("known hosts = %q, want %q", b.KnownHosts, want)
+	}
+	for i := range want {
+		if b.KnownHosts[i] != want[i] {
+			t.Fatalf("known hosts = %q, want %q", b.KnownHosts, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"goterm/backend/internal/common"
+	"goterm/backend/internal/profiles"
+	"goterm/backend/internal/security/hostkey"
+	"goterm/backend/internal/security/keyring"
+	"goterm/backend/internal/security/secretbox"
+)
+
+// Conflict strategies for profiles that already exist locally, by ID or name.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+const (
+	ActionCreated     = "created"
+	ActionOverwritten = "overwritten"
+	ActionRenamed     = "renamed"
+	ActionSkipped     = "skipped"
+)
+
+const (
+	KindSSH   = "ssh"
+	KindMySQL = "mysql"
+)
+
+type ImportOptions struct {
+	Conflict   string `json:"conflict"`
+	Passphrase string `json:"passphrase"`
+	KnownHosts bool   `json:"knownHosts"`
+}
+
+type ImportItem struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Action string `json:"action"`
+}
+
+type ImportReport struct {
+	Items           []ImportItem `json:"items"`
+	KnownHostsAdded int          `json:"knownHostsAdded"`
+	SecretsRestored int          `json:"secretsRestored"`
+	// SecretsLocked is set when the bundle carries secrets but no passphrase
+	// was given, so they were left out.
+	SecretsLocked bool `json:"secretsLocked"`
+}
+
+// existing indexes local profiles for conflict detection.
+type existing struct {
+	ids   map[string]bool
+	names map[string]string
+}
+
+func newExisting() *existing {
+	return &existing{ids: map[string]bool{}, names: map[string]string{}}
+}
+
+func (e *existing) add(id, name string) {
+	e.ids[id] = true
+	e.names[name] = id
+}
+
+// conflict returns the local ID an incoming profile collides with.
+func (e *existing) conflict(id, name string) (string, bool) {
+	if e.ids[id] {
+		return id, true
+	}
+	if localID, ok := e.names[name]; ok {
+		return localID, true
+	}
+	return "", false
+}
+
+func (e *existing) uniqueName(name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if _, ok := e.names[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// plan decides the local ID, name and action for one incoming profile.
+func (e *existing) plan(id, name, strategy string) (string, string, string, error) {
+	localID, conflict := e.conflict(id, name)
+	if !conflict {
+		if id == "" {
+			var err error
+			if id, err = common.NewID(); err != nil {
+				return "", "", "", err
+			}
+		}
+		e.add(id, name)
+		return id, name, ActionCreated, nil
+	}
+
+	switch strategy {
+	case ConflictOverwrite:
+		return localID, name, ActionOverwritten, nil
+	case ConflictRename:
+		newID, err := common.NewID()
+		if err != nil {
+			return "", "", "", err
+		}
+		name = e.uniqueName(name)
+		e.add(newID, name)
+		return newID, name, ActionRenamed, nil
+	default:
+		return localID, name, ActionSkipped, nil
+	}
+}
+
+func (s *Service) Import(ctx context.Context, data []byte, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{Items: []ImportItem{}}
+
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return report, fmt.Errorf("unsupported conflict strategy: %s", opts.Conflict)
+	}
+
+	b, err := decode(data)
+	if err != nil {
+		return report, err
+	}
+
+	var secret secrets
+	if b.Secrets != nil {
+		if opts.Passphrase == "" {
+			report.SecretsLocked = true
+		} else {
+			plain, err := secretbox.Open(opts.Passphrase, b.Secrets)
+			if err != nil {
+				return report, err
+			}
+			if err := json.Unmarshal(plain, &secret); err != nil {
+				return report, fmt.Errorf("parse bundle secrets: %w", err)
+			}
+		}
+	}
+
+	localProfiles, err := s.profiles.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	sshExisting := newExisting()
+	for _, profile := range localProfiles {
+		sshExisting.add(profile.ID, profile.Name)
+	}
+
+	// Decide every ID first so jump host references can be rewritten.
+	idMap := map[string]string{}
+	planned := make([]profiles.Profile, 0, len(b.Profiles))
+	actions := make([]string, 0, len(b.Profiles))
+	for _, profile := range b.Profiles {
+		id, name, action, err := sshExisting.plan(profile.ID, profile.Name, opts.Conflict)
+		if err != nil {
+			return report, err
+		}
+		idMap[profile.ID] = id
+		profile.ID, profile.Name = id, name
+		planned = append(planned, profile)
+		actions = append(actions, action)
+	}
+
+	for i, profile := range planned {
+		action := actions[i]
+		report.Items = append(report.Items, ImportItem{Kind: KindSSH, Name: profile.Name, ID: profile.ID, Action: action})
+		if action == ActionSkipped {
+			continue
+		}
+
+		jumps := make([]string, 0, len(profile.JumpProfileIDs))
+		for _, jumpID := range profile.JumpProfileIDs {
+			if mapped, ok := idMap[jumpID]; ok {
+				jumps = append(jumps, mapped)
+			} else if sshExisting.ids[jumpID] {
+				jumps = append(jumps, jumpID)
+			}
+		}
+		profile.JumpProfileIDs = jumps
+
+		if _, err := s.profiles.Save(ctx, profile); err != nil {
+			return report, err
+		}
+		if err := restoreSecret(&report, secret.Passwords, b.Profiles[i].ID, profile.ID, keyring.SetPassword); err != nil {
+			return report, err
+		}
+		if err := restoreSecret(&report, secret.Passphrases, b.Profiles[i].ID, profile.ID, keyring.SetPrivateKeyPassphrase); err != nil {
+			return report, err
+		}
+	}
+
+	localMySQL, err := s.mysql.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	mysqlExisting := newExisting()
+	for _, profile := range localMySQL {
+		mysqlExisting.add(profile.ID, profile.Name)
+	}
+
+	for _, profile := range b.MySQLProfiles {
+		originalID := profile.ID
+		id, name, action, err := mysqlExisting.plan(profile.ID, profile.Name, opts.Conflict)
+		if err != nil {
+			return report, err
+		}
+		report.Items = append(report.Items, ImportItem{Kind: KindMySQL, Name: name, ID: id, Action: action})
+		if action == ActionSkipped {
+			continue
+		}
+
+		profile.ID, profile.Name = id, name
+		if mapped, ok := idMap[profile.SSHProfileID]; ok {
+			profile.SSHProfileID = mapped
+		}
+		if _, err := s.mysql.Save(ctx, profile); err != nil {
+			return report, err
+		}
+		if err := restoreSecret(&report, secret.MySQLPasswords, originalID, profile.ID, keyring.SetMySQLPassword); err != nil {
+			return report, err
+		}
+	}
+
+	if opts.KnownHosts && len(b.KnownHosts) > 0 {
+		report.KnownHostsAdded, err = hostkey.MergeLines(s.knownHostsPath, b.KnownHosts)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func restoreSecret(report *ImportReport, values map[string]string, bundleID, localID string, set func(profileID, value string) error) error {
+	value, ok := values[bundleID]
+	if !ok || value == "" {
+		return nil
+	}
+	if err := set(localID, value); err != nil {
+		return fmt.Errorf("store secret: %w", err)
+	}
+	report.SecretsRestored++
+	return nil
+}
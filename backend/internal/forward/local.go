@@ -0,0 +1,72 @@
+package forward
+
+import (
+	"errors"
+	"net"
+)
+
+// StartLocal listens on listenAddr and tunnels every accepted connection to
+// targetAddr through the session's SSH client, like ssh -L.
+func (m *Manager) StartLocal(sessionID, listenAddr, targetAddr string) (string, error) {
+	if targetAddr == "" {
+		return "", errors.New("target address is required")
+	}
+	if _, _, err := net.SplitHostPort(targetAddr); err != nil {
+		return "", err
+	}
+	listenAddr, err := normalizeListenAddr(listenAddr)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.provider.GetClient(sessionID); err != nil {
+		return "", err
+	}
+
+	f, err := newForwarder(sessionID, "local", listenAddr, targetAddr)
+	if err != nil {
+		return "", err
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return "", err
+	}
+	f.listener = listener
+	f.info.ListenAddr = listener.Addr().String()
+
+	m.add(f)
+	go m.acceptLocal(f)
+
+	return f.info.ID, nil
+}
+
+func (m *Manager) acceptLocal(f *forwarder) {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			if !f.closed() {
+				m.fail(f, err)
+			}
+			return
+		}
+		go m.handleLocal(f, conn)
+	}
+}
+
+func (m *Manager) handleLocal(f *forwarder, conn net.Conn) {
+	client, err := m.provider.GetClient(f.info.SessionID)
+	if err != nil {
+		_ = conn.Close()
+		m.setLastError(f, err)
+		return
+	}
+
+	remote, err := client.Dial("tcp", f.info.TargetAddr)
+	if err != nil {
+		_ = conn.Close()
+		m.setLastError(f, err)
+		return
+	}
+
+	f.pipe(conn, remote)
+}
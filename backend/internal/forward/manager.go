@@ -0,0 +1,252 @@
+package forward
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/common"
+)
+
+type ClientProvider interface {
+	GetClient(sessionID string) (*ssh.Client, error)
+}
+
+type Forward struct {
+	ID                string `json:"id"`
+	SessionID         string `json:"sessionId"`
+	Kind              string `json:"kind"`
+	ListenAddr        string `json:"listenAddr"`
+	TargetAddr        string `json:"targetAddr"`
+	State             string `json:"state"`
+	LastError         string `json:"lastError"`
+	BytesIn           int64  `json:"bytesIn"`
+	BytesOut          int64  `json:"bytesOut"`
+	Connections       int64  `json:"connections"`
+	ActiveConnections int64  `json:"activeConnections"`
+}
+
+type StateEvent struct {
+	ForwardID string `json:"forwardId"`
+	SessionID string `json:"sessionId"`
+	Kind      string `json:"kind"`
+	State     string `json:"state"`
+	Error     string `json:"error"`
+}
+
+type forwarder struct {
+	info     Forward
+	listener net.Listener
+	closing  chan struct{}
+
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	connections atomic.Int64
+	active      atomic.Int64
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]struct{}
+
+	// Dynamic forwards only.
+	auth   *socksAuth
+	destMu sync.Mutex
+	dests  map[string]*destination
+}
+
+type destination struct {
+	addr string
+
+	connections atomic.Int64
+	active      atomic.Int64
+	failures    atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	lastUsed    atomic.Int64
+}
+
+type Manager struct {
+	provider ClientProvider
+	emitter  common.Emitter
+
+	mu       sync.Mutex
+	forwards map[string]*forwarder
+}
+
+func NewManager(provider ClientProvider, emitter common.Emitter) *Manager {
+	if emitter == nil {
+		emitter = common.NopEmitter{}
+	}
+	return &Manager{
+		provider: provider,
+		emitter:  emitter,
+		forwards: map[string]*forwarder{},
+	}
+}
+
+func (m *Manager) List() []Forward {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := make([]Forward, 0, len(m.forwards))
+	for _, f := range m.forwards {
+		items = append(items, f.snapshot())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ListenAddr < items[j].ListenAddr
+	})
+	return items
+}
+
+// Stop closes the forward and removes it from the list.
+func (m *Manager) Stop(forwardID string) error {
+	m.mu.Lock()
+	f, ok := m.forwards[forwardID]
+	if ok {
+		delete(m.forwards, forwardID)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return common.ErrNotFound
+	}
+
+	if m.shutdown(f) {
+		m.setState(f, "stopped", "")
+	}
+	return nil
+}
+
+// SessionClosed shuts down every forward bound to sessionID. The forwards stay
+// listed so the UI can show why they stopped.
+func (m *Manager) SessionClosed(sessionID string) {
+	for _, f := range m.bySession(sessionID) {
+		if m.shutdown(f) {
+			m.setState(f, "stopped", "session disconnected")
+		}
+	}
+}
+
+func (m *Manager) bySession(sessionID string) []*forwarder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []*forwarder
+	for _, f := range m.forwards {
+		if f.info.SessionID == sessionID {
+			items = append(items, f)
+		}
+	}
+	return items
+}
+
+func (m *Manager) add(f *forwarder) {
+	m.mu.Lock()
+	m.forwards[f.info.ID] = f
+	m.mu.Unlock()
+	m.setState(f, f.info.State, "")
+}
+
+// shutdown closes the listener and every open connection of f. It reports
+// whether this call did the closing.
+func (m *Manager) shutdown(f *forwarder) bool {
+	m.mu.Lock()
+	select {
+	case <-f.closing:
+		m.mu.Unlock()
+		return false
+	default:
+	}
+	close(f.closing)
+	listener := f.listener
+	m.mu.Unlock()
+
+	if listener != nil {
+		_ = listener.Close()
+	}
+	f.closeConns()
+	return true
+}
+
+// fail records a listener error; the forward stays listed in the error state.
+func (m *Manager) fail(f *forwarder, err error) {
+	if m.shutdown(f) {
+		m.setState(f, "error", err.Error())
+	}
+}
+
+func (m *Manager) setState(f *forwarder, state, errMsg string) {
+	m.mu.Lock()
+	f.info.State = state
+	if errMsg != "" {
+		f.info.LastError = errMsg
+	}
+	event := StateEvent{
+		ForwardID: f.info.ID,
+		SessionID: f.info.SessionID,
+		Kind:      f.info.Kind,
+		State:     state,
+		Error:     errMsg,
+	}
+	m.mu.Unlock()
+
+	m.emitter.Emit("forward:state", event)
+}
+
+func (m *Manager) setLastError(f *forwarder, err error) {
+	m.mu.Lock()
+	f.info.LastError = err.Error()
+	m.mu.Unlock()
+}
+
+func newForwarder(sessionID, kind, listenAddr, targetAddr string) (*forwarder, error) {
+	id, err := common.NewID()
+	if err != nil {
+		return nil, err
+	}
+	return &forwarder{
+		info: Forward{
+			ID:         id,
+			SessionID:  sessionID,
+			Kind:       kind,
+			ListenAddr: listenAddr,
+			TargetAddr: targetAddr,
+			State:      "listening",
+		},
+		closing: make(chan struct{}),
+		conns:   map[net.Conn]struct{}{},
+		dests:   map[string]*destination{},
+	}, nil
+}
+
+func (f *forwarder) snapshot() Forward {
+	info := f.info
+	info.BytesIn = f.bytesIn.Load()
+	info.BytesOut = f.bytesOut.Load()
+	info.Connections = f.connections.Load()
+	info.ActiveConnections = f.active.Load()
+	return info
+}
+
+func (f *forwarder) closed() bool {
+	select {
+	case <-f.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeListenAddr binds to the loopback interface when no host is given,
+// matching ssh -L and -R.
+func normalizeListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,96 @@
+package forward
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+)
+
+// pipe copies between an accepted connection and the connection dialed for it
+// until either side closes. Bytes travelling towards the target are counted as
+// out, bytes coming back as in.
+func (f *forwarder) pipe(accepted, dialed net.Conn) {
+	f.pipeVia(accepted, dialed, nil)
+}
+
+// pipeVia is pipe that also accounts the connection to dest when it is set.
+func (f *forwarder) pipeVia(accepted, dialed net.Conn, dest *destination) {
+	if !f.track(accepted) || !f.track(dialed) {
+		_ = accepted.Close()
+		_ = dialed.Close()
+		f.untrack(accepted)
+		return
+	}
+	defer f.untrack(accepted)
+	defer f.untrack(dialed)
+
+	f.connections.Add(1)
+	f.active.Add(1)
+	defer f.active.Add(-1)
+
+	out := &countingReader{r: accepted, n: &f.bytesOut}
+	in := &countingReader{r: dialed, n: &f.bytesIn}
+	if dest != nil {
+		dest.connections.Add(1)
+		dest.active.Add(1)
+		defer dest.active.Add(-1)
+		out.extra = &dest.bytesOut
+		in.extra = &dest.bytesIn
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(dialed, out)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(accepted, in)
+		done <- struct{}{}
+	}()
+
+	<-done
+	_ = accepted.Close()
+	_ = dialed.Close()
+	<-done
+}
+
+func (f *forwarder) track(conn net.Conn) bool {
+	f.connsMu.Lock()
+	defer f.connsMu.Unlock()
+	if f.closed() {
+		return false
+	}
+	f.conns[conn] = struct{}{}
+	return true
+}
+
+func (f *forwarder) untrack(conn net.Conn) {
+	f.connsMu.Lock()
+	delete(f.conns, conn)
+	f.connsMu.Unlock()
+}
+
+func (f *forwarder) closeConns() {
+	f.connsMu.Lock()
+	defer f.connsMu.Unlock()
+	for conn := range f.conns {
+		_ = conn.Close()
+	}
+}
+
+type countingReader struct {
+	r     io.Reader
+	n     *atomic.Int64
+	extra *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.n.Add(int64(n))
+		if c.extra != nil {
+			c.extra.Add(int64(n))
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,118 @@
+package forward
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+const remoteDialTimeout = 10 * time.Second
+
+// StartRemote asks the SSH server to listen on listenAddr and proxies every
+// connection it accepts to targetAddr on this machine, like ssh -R.
+func (m *Manager) StartRemote(sessionID, listenAddr, targetAddr string) (string, error) {
+	if targetAddr == "" {
+		return "", errors.New("target address is required")
+	}
+	if _, _, err := net.SplitHostPort(targetAddr); err != nil {
+		return "", err
+	}
+	listenAddr, err := normalizeListenAddr(listenAddr)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := m.provider.GetClient(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := newForwarder(sessionID, "remote", listenAddr, targetAddr)
+	if err != nil {
+		return "", err
+	}
+
+	listener, err := client.Listen("tcp", listenAddr)
+	if err != nil {
+		return "", err
+	}
+	f.listener = listener
+	f.info.ListenAddr = listener.Addr().String()
+
+	m.add(f)
+	go m.acceptRemote(f, listener)
+
+	return f.info.ID, nil
+}
+
+// SessionRestored re-opens the remote listeners of sessionID that were lost
+// while its connection was down.
+func (m *Manager) SessionRestored(sessionID string) {
+	for _, f := range m.bySession(sessionID) {
+		if f.info.Kind != "remote" || m.state(f) != "suspended" {
+			continue
+		}
+		if err := m.relisten(f); err != nil {
+			m.setState(f, "suspended", err.Error())
+		}
+	}
+}
+
+func (m *Manager) acceptRemote(f *forwarder, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if f.closed() {
+				return
+			}
+			// The listener dies with the SSH client. Try the current client
+			// and otherwise wait for the session to come back.
+			if relistenErr := m.relisten(f); relistenErr != nil {
+				m.setState(f, "suspended", err.Error())
+			}
+			return
+		}
+		go m.handleRemote(f, conn)
+	}
+}
+
+func (m *Manager) relisten(f *forwarder) error {
+	client, err := m.provider.GetClient(f.info.SessionID)
+	if err != nil {
+		return err
+	}
+	listener, err := client.Listen("tcp", f.info.ListenAddr)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if f.closed() {
+		m.mu.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	f.listener = listener
+	m.mu.Unlock()
+
+	m.setState(f, "listening", "")
+	go m.acceptRemote(f, listener)
+	return nil
+}
+
+func (m *Manager) handleRemote(f *forwarder, conn net.Conn) {
+	local, err := net.DialTimeout("tcp", f.info.TargetAddr, remoteDialTimeout)
+	if err != nil {
+		_ = conn.Close()
+		m.setLastError(f, err)
+		return
+	}
+
+	f.pipe(conn, local)
+}
+
+func (m *Manager) state(f *forwarder) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return f.info.State
+}
@@ -0,0 +1,324 @@
+package forward
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"goterm/backend/internal/common"
+)
+
+const (
+	socksVersion          = 0x05
+	socksAuthVersion      = 0x01
+	socksHandshakeTimeout = 10 * time.Second
+
+	socksMethodNone         = 0x00
+	socksMethodPassword     = 0x02
+	socksMethodNoAcceptable = 0xff
+
+	socksCmdConnect = 0x01
+
+	socksAtypIPv4   = 0x01
+	socksAtypDomain = 0x03
+	socksAtypIPv6   = 0x04
+
+	socksReplySucceeded        = 0x00
+	socksReplyHostUnreachable  = 0x04
+	socksReplyCmdNotSupported  = 0x07
+	socksReplyAtypNotSupported = 0x08
+)
+
+type DestinationStats struct {
+	Destination       string `json:"destination"`
+	Connections       int64  `json:"connections"`
+	ActiveConnections int64  `json:"activeConnections"`
+	Failures          int64  `json:"failures"`
+	BytesIn           int64  `json:"bytesIn"`
+	BytesOut          int64  `json:"bytesOut"`
+	LastUsed          int64  `json:"lastUsed"`
+}
+
+type socksAuth struct {
+	username string
+	password string
+}
+
+// StartDynamic runs a SOCKS5 server on listenAddr whose outbound connections
+// are dialed through the session's SSH client, like ssh -D. When username is
+// set clients must authenticate with it and password.
+func (m *Manager) StartDynamic(sessionID, listenAddr, username, password string) (string, error) {
+	listenAddr, err := normalizeListenAddr(listenAddr)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.provider.GetClient(sessionID); err != nil {
+		return "", err
+	}
+
+	f, err := newForwarder(sessionID, "dynamic", listenAddr, "")
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		f.auth = &socksAuth{username: username, password: password}
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return "", err
+	}
+	f.listener = listener
+	f.info.ListenAddr = listener.Addr().String()
+
+	m.add(f)
+	go m.acceptDynamic(f)
+
+	return f.info.ID, nil
+}
+
+// Destinations returns per-destination connection stats of a dynamic forward.
+func (m *Manager) Destinations(forwardID string) ([]DestinationStats, error) {
+	m.mu.Lock()
+	f, ok := m.forwards[forwardID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+
+	f.destMu.Lock()
+	items := make([]DestinationStats, 0, len(f.dests))
+	for _, d := range f.dests {
+		items = append(items, d.snapshot())
+	}
+	f.destMu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Connections != items[j].Connections {
+			return items[i].Connections > items[j].Connections
+		}
+		return items[i].Destination < items[j].Destination
+	})
+	return items, nil
+}
+
+func (m *Manager) acceptDynamic(f *forwarder) {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			if !f.closed() {
+				m.fail(f, err)
+			}
+			return
+		}
+		go m.handleSocks(f, conn)
+	}
+}
+
+func (m *Manager) handleSocks(f *forwarder, conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+	reader := bufio.NewReader(conn)
+
+	if err := socksNegotiate(reader, conn, f.auth); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	addr, reply, err := socksReadRequest(reader)
+	if err != nil {
+		if reply != 0 {
+			_ = socksWriteReply(conn, reply)
+		}
+		_ = conn.Close()
+		return
+	}
+
+	dest := f.destination(addr)
+	client, err := m.provider.GetClient(f.info.SessionID)
+	if err == nil {
+		var remote net.Conn
+		remote, err = client.Dial("tcp", addr)
+		if err == nil {
+			if err := socksWriteReply(conn, socksReplySucceeded); err != nil {
+				_ = remote.Close()
+				_ = conn.Close()
+				return
+			}
+			_ = conn.SetDeadline(time.Time{})
+			f.pipeVia(&bufferedConn{Conn: conn, r: reader}, remote, dest)
+			return
+		}
+	}
+
+	dest.failures.Add(1)
+	m.setLastError(f, err)
+	_ = socksWriteReply(conn, socksReplyHostUnreachable)
+	_ = conn.Close()
+}
+
+func socksNegotiate(r *bufio.Reader, w io.Writer, auth *socksAuth) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if header[0] != socksVersion {
+		return fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return err
+	}
+
+	want := byte(socksMethodNone)
+	if auth != nil {
+		want = socksMethodPassword
+	}
+	offered := false
+	for _, method := range methods {
+		if method == want {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		_, _ = w.Write([]byte{socksVersion, socksMethodNoAcceptable})
+		return errors.New("no acceptable socks auth method")
+	}
+	if _, err := w.Write([]byte{socksVersion, want}); err != nil {
+		return err
+	}
+	if auth == nil {
+		return nil
+	}
+
+	// RFC 1929 username/password sub-negotiation.
+	version, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if version != socksAuthVersion {
+		return fmt.Errorf("unsupported socks auth version %d", version)
+	}
+	username, err := readSocksString(r)
+	if err != nil {
+		return err
+	}
+	password, err := readSocksString(r)
+	if err != nil {
+		return err
+	}
+
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(auth.username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(auth.password)) == 1
+	if !userOK || !passOK {
+		_, _ = w.Write([]byte{socksAuthVersion, 0x01})
+		return errors.New("socks authentication failed")
+	}
+	_, err = w.Write([]byte{socksAuthVersion, 0x00})
+	return err
+}
+
+// socksReadRequest parses a request and returns the destination address. On
+// failure the returned reply code, if non-zero, should be sent to the client.
+func socksReadRequest(r *bufio.Reader) (string, byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, err
+	}
+	if header[0] != socksVersion {
+		return "", 0, fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	if header[1] != socksCmdConnect {
+		return "", socksReplyCmdNotSupported, fmt.Errorf("unsupported socks command %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case socksAtypIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case socksAtypIPv6:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case socksAtypDomain:
+		name, err := readSocksString(r)
+		if err != nil {
+			return "", 0, err
+		}
+		host = name
+	default:
+		return "", socksReplyAtypNotSupported, fmt.Errorf("unsupported socks address type %d", header[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return "", 0, err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), 0, nil
+}
+
+func socksWriteReply(w io.Writer, reply byte) error {
+	// The bound address is not meaningful for a tunnelled connection.
+	_, err := w.Write([]byte{socksVersion, reply, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func readSocksString(r *bufio.Reader) (string, error) {
+	length, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// bufferedConn reads through the handshake reader so bytes the client sent
+// early are not lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (f *forwarder) destination(addr string) *destination {
+	f.destMu.Lock()
+	defer f.destMu.Unlock()
+	d, ok := f.dests[addr]
+	if !ok {
+		d = &destination{addr: addr}
+		f.dests[addr] = d
+	}
+	d.lastUsed.Store(time.Now().UnixMilli())
+	return d
+}
+
+func (d *destination) snapshot() DestinationStats {
+	return DestinationStats{
+		Destination:       d.addr,
+		Connections:       d.connections.Load(),
+		ActiveConnections: d.active.Load(),
+		Failures:          d.failures.Load(),
+		BytesIn:           d.bytesIn.Load(),
+		BytesOut:          d.bytesOut.Load(),
+		LastUsed:          d.lastUsed.Load(),
+	}
+}
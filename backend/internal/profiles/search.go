@@ -0,0 +1,129 @@
+package profiles
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	SortName     = "name"
+	SortRecent   = "recent"
+	SortFrequent = "frequent"
+)
+
+// SearchQuery filters profiles. Text matches name or host fuzzily, every tag
+// in Tags must be present, and Sort defaults to relevance when Text is set
+// and to name otherwise.
+type SearchQuery struct {
+	Text          string   `json:"text"`
+	Tags          []string `json:"tags"`
+	FavoritesOnly bool     `json:"favoritesOnly"`
+	Sort          string   `json:"sort"`
+	Limit         int      `json:"limit"`
+}
+
+// Filter applies query to items. Stores without native search can implement
+// Search with List and Filter.
+func Filter(items []Profile, query SearchQuery) []Profile {
+	text := strings.ToLower(strings.TrimSpace(query.Text))
+
+	type scored struct {
+		profile Profile
+		score   int
+	}
+	var matched []scored
+	for _, profile := range items {
+		if query.FavoritesOnly && !profile.Favorite {
+			continue
+		}
+		if !hasTags(profile, query.Tags) {
+			continue
+		}
+		score := 0
+		if text != "" {
+			score = max(fuzzyScore(text, strings.ToLower(profile.Name)), fuzzyScore(text, strings.ToLower(profile.Host)))
+			if score < 0 {
+				continue
+			}
+		}
+		matched = append(matched, scored{profile: profile, score: score})
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		switch query.Sort {
+		case SortRecent:
+			if a.profile.LastConnectedAt != b.profile.LastConnectedAt {
+				return a.profile.LastConnectedAt > b.profile.LastConnectedAt
+			}
+		case SortFrequent:
+			if a.profile.ConnectCount != b.profile.ConnectCount {
+				return a.profile.ConnectCount > b.profile.ConnectCount
+			}
+		case SortName:
+		default:
+			if a.score != b.score {
+				return a.score > b.score
+			}
+		}
+		return strings.ToLower(a.profile.Name) < strings.ToLower(b.profile.Name)
+	})
+
+	out := make([]Profile, 0, len(matched))
+	for _, item := range matched {
+		if query.Limit > 0 && len(out) == query.Limit {
+			break
+		}
+		out = append(out, item.profile)
+	}
+	return out
+}
+
+func hasTags(profile Profile, tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range profile.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyScore rates how well pattern matches s, or returns -1 when the
+// characters of pattern do not appear in s in order. Substring matches beat
+// scattered ones, prefixes beat the rest, and tighter matches score higher.
+func fuzzyScore(pattern, s string) int {
+	if idx := strings.Index(s, pattern); idx >= 0 {
+		score := 1000 - idx
+		if idx == 0 {
+			score += 500
+		}
+		return score
+	}
+
+	score := 500
+	pos := 0
+	last := -1
+	for _, r := range pattern {
+		idx := strings.IndexRune(s[pos:], r)
+		if idx < 0 {
+			return -1
+		}
+		at := pos + idx
+		if last >= 0 {
+			score -= at - last - 1
+		}
+		last = at
+		pos = at + len(string(r))
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
@@ -0,0 +1,352 @@
+package sshconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goterm/backend/internal/profiles"
+)
+
+const (
+	ActionCreate = "create"
+	ActionSkip   = "skip"
+)
+
+type Options struct {
+	// Path defaults to ~/.ssh/config.
+	Path   string `json:"path"`
+	Group  string `json:"group"`
+	DryRun bool   `json:"dryRun"`
+}
+
+// Entry describes what happened, or would happen in a dry run, to one host.
+// Jumps lists the ProxyJump hops by name since dry runs have no profile IDs.
+type Entry struct {
+	Alias   string           `json:"alias"`
+	Action  string           `json:"action"`
+	Reason  string           `json:"reason,omitempty"`
+	Profile profiles.Profile `json:"profile"`
+	Jumps   []string         `json:"jumps,omitempty"`
+}
+
+type Report struct {
+	Path        string      `json:"path"`
+	DryRun      bool        `json:"dryRun"`
+	Entries     []Entry     `json:"entries"`
+	Unsupported []Directive `json:"unsupported"`
+}
+
+type importer struct {
+	ctx   context.Context
+	store profiles.Store
+	opts  Options
+	cfg   *config
+	home  string
+	local string
+
+	// known maps host/port/user keys to the name and ID of the profile that
+	// already covers them, from the store or from this import.
+	known     map[string]knownProfile
+	resolved  map[string]string
+	resolving map[string]bool
+	// skipped holds the specs that could not be imported, so hosts that jump
+	// through them are skipped too.
+	skipped map[string]bool
+	report  *Report
+}
+
+type knownProfile struct {
+	name string
+	id   string
+}
+
+// Import reads an OpenSSH client config and saves one profile per concrete
+// Host alias. Hosts already present in store, matched by address, port and
+// user, are skipped. With DryRun set nothing is saved.
+func Import(ctx context.Context, store profiles.Store, opts Options) (Report, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Report{}, err
+	}
+	if opts.Path == "" {
+		opts.Path = filepath.Join(home, ".ssh", "config")
+	}
+	opts.Path = expandTilde(opts.Path, home)
+
+	cfg, err := parseFile(opts.Path, home)
+	if err != nil {
+		return Report{}, err
+	}
+
+	existing, err := store.List(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := &Report{
+		Path:        opts.Path,
+		DryRun:      opts.DryRun,
+		Entries:     []Entry{},
+		Unsupported: cfg.unsupported,
+	}
+	imp := &importer{
+		ctx:       ctx,
+		store:     store,
+		opts:      opts,
+		cfg:       cfg,
+		home:      home,
+		local:     localUser(),
+		known:     map[string]knownProfile{},
+		resolved:  map[string]string{},
+		resolving: map[string]bool{},
+		skipped:   map[string]bool{},
+		report:    report,
+	}
+	for _, profile := range existing {
+		imp.known[profileKey(profile.Host, profile.Port, profile.Username)] = knownProfile{name: profile.Name, id: profile.ID}
+	}
+
+	for _, alias := range cfg.aliases() {
+		if _, err := imp.resolve(alias); err != nil {
+			return *report, err
+		}
+	}
+	return *report, nil
+}
+
+// aliases returns the concrete Host names in file order.
+func (c *config) aliases() []string {
+	seen := map[string]bool{}
+	var aliases []string
+	for _, b := range c.blocks {
+		for _, pattern := range b.patterns {
+			if isPattern(pattern) || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			aliases = append(aliases, pattern)
+		}
+	}
+	return aliases
+}
+
+// settings collects the options that apply to alias, keeping the first value
+// of each keyword.
+func (c *config) settings(alias string) map[string]string {
+	values := map[string]string{}
+	for _, b := range c.blocks {
+		if !b.matches(alias) {
+			continue
+		}
+		for _, opt := range b.options {
+			if _, ok := values[opt.keyword]; !ok {
+				values[opt.keyword] = opt.value
+			}
+		}
+	}
+	return values
+}
+
+// resolve imports the host named spec, which is either a Host alias or a
+// ProxyJump hop of the form [user@]host[:port], and returns its profile ID.
+// Hops are imported before the hosts that use them.
+func (imp *importer) resolve(spec string) (string, error) {
+	if id, ok := imp.resolved[spec]; ok {
+		return id, nil
+	}
+	if imp.resolving[spec] {
+		return "", fmt.Errorf("ssh config: ProxyJump loop through %q", spec)
+	}
+	imp.resolving[spec] = true
+	defer delete(imp.resolving, spec)
+
+	alias, userOverride, portOverride := splitHop(spec)
+	values := imp.cfg.settings(alias)
+
+	entry := Entry{Alias: spec, Action: ActionCreate}
+	profile, err := imp.profile(alias, values)
+	if err != nil {
+		return imp.skip(spec, entry, err.Error())
+	}
+	profile.Name = spec
+	if userOverride != "" {
+		profile.Username = userOverride
+	}
+	if portOverride != "" {
+		port, err := strconv.Atoi(portOverride)
+		if err != nil {
+			return imp.skip(spec, entry, fmt.Sprintf("invalid port %q", portOverride))
+		}
+		profile.Port = port
+	}
+
+	key := profileKey(profile.Host, profile.Port, profile.Username)
+	if known, ok := imp.known[key]; ok {
+		entry.Action, entry.Reason = ActionSkip, fmt.Sprintf("duplicate of profile %q", known.name)
+		profile.ID = known.id
+		entry.Profile = profile
+		imp.report.Entries = append(imp.report.Entries, entry)
+		imp.resolved[spec] = known.id
+		return known.id, nil
+	}
+
+	if jumps := values["proxyjump"]; jumps != "" && !strings.EqualFold(jumps, "none") {
+		for _, hop := range strings.Split(jumps, ",") {
+			hop = strings.TrimSpace(hop)
+			if hop == "" {
+				continue
+			}
+			id, err := imp.resolve(hop)
+			if err != nil {
+				return "", err
+			}
+			entry.Jumps = append(entry.Jumps, hop)
+			// Without the hop the profile would connect directly, which is
+			// not the route the config describes.
+			if imp.skipped[hop] {
+				entry.Profile = profile
+				return imp.skip(spec, entry, fmt.Sprintf("jump host %q was not imported", hop))
+			}
+			if id != "" {
+				profile.JumpProfileIDs = append(profile.JumpProfileIDs, id)
+			}
+		}
+	}
+
+	if !imp.opts.DryRun {
+		id, err := imp.store.Save(imp.ctx, profile)
+		if err != nil {
+			return "", err
+		}
+		profile.ID = id
+	}
+
+	entry.Profile = profile
+	imp.report.Entries = append(imp.report.Entries, entry)
+	imp.known[key] = knownProfile{name: spec, id: profile.ID}
+	imp.resolved[spec] = profile.ID
+	return profile.ID, nil
+}
+
+// skip reports spec as not imported.
+func (imp *importer) skip(spec string, entry Entry, reason string) (string, error) {
+	entry.Action, entry.Reason = ActionSkip, reason
+	imp.report.Entries = append(imp.report.Entries, entry)
+	imp.resolved[spec] = ""
+	imp.skipped[spec] = true
+	return "", nil
+}
+
+func (imp *importer) profile(alias string, values map[string]string) (profiles.Profile, error) {
+	profile := profiles.Profile{
+		Name:     alias,
+		Group:    imp.opts.Group,
+		Host:     alias,
+		Port:     22,
+		Username: imp.local,
+	}
+
+	if hostname := values["hostname"]; hostname != "" {
+		profile.Host = strings.ReplaceAll(hostname, "%h", alias)
+	}
+	if port := values["port"]; port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return profile, fmt.Errorf("invalid port %q", port)
+		}
+		profile.Port = n
+	}
+	if user := values["user"]; user != "" {
+		profile.Username = user
+	}
+
+	if identity := values["identityfile"]; identity != "" && !strings.EqualFold(identity, "none") {
+		profile.AuthType = "privateKey"
+		profile.PrivateKeyPath = imp.expandPath(identity, profile)
+	} else if keyPath := imp.defaultIdentity(); keyPath != "" {
+		profile.AuthType = "privateKey"
+		profile.PrivateKeyPath = keyPath
+	} else {
+		profile.AuthType = "password"
+		profile.UseKeyring = true
+	}
+	if cert := values["certificatefile"]; cert != "" && !strings.EqualFold(cert, "none") {
+		profile.CertificatePath = imp.expandPath(cert, profile)
+	}
+
+	if command := values["proxycommand"]; command != "" && !strings.EqualFold(command, "none") {
+		profile.ProxyCommand = command
+	}
+	profile.ForwardAgent = strings.EqualFold(values["forwardagent"], "yes")
+	return profile, nil
+}
+
+// expandPath resolves "~" and the %d, %u, %h and %r tokens OpenSSH allows in
+// IdentityFile and CertificateFile.
+func (imp *importer) expandPath(path string, profile profiles.Profile) string {
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", imp.home,
+		"%u", imp.local,
+		"%h", profile.Host,
+		"%r", profile.Username,
+	)
+	return expandTilde(replacer.Replace(path), imp.home)
+}
+
+// defaultIdentity returns the first of the keys ssh tries by default that
+// exists.
+func (imp *importer) defaultIdentity() string {
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := filepath.Join(imp.home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// splitHop splits a ProxyJump hop into host, user and port. Plain aliases
+// come back unchanged.
+func splitHop(spec string) (host, user, port string) {
+	host = spec
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		user, host = host[:at], host[at+1:]
+	}
+	if strings.HasPrefix(host, "[") {
+		if end := strings.Index(host, "]"); end > 0 {
+			rest := host[end+1:]
+			host = host[1:end]
+			port = strings.TrimPrefix(rest, ":")
+		}
+	} else if colon := strings.LastIndex(host, ":"); colon >= 0 && strings.Count(host, ":") == 1 {
+		host, port = host[:colon], host[colon+1:]
+	}
+	return host, user, port
+}
+
+// profileKey identifies a destination. Port 0 means the default port, so it
+// keys the same as 22.
+func profileKey(host string, port int, username string) string {
+	if port == 0 {
+		port = 22
+	}
+	return strings.ToLower(host) + "|" + strconv.Itoa(port) + "|" + username
+}
+
+func localUser() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	name := current.Username
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
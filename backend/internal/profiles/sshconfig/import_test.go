@@ -0,0 +1,73 @@
+package sshconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goterm/backend/internal/profiles"
+)
+
+type memoryStore struct {
+	profiles.Store
+	saved []profiles.Profile
+}
+
+func (s *memoryStore) List(context.Context) ([]profiles.Profile, error) {
+	return s.saved, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, p profiles.Profile) (string, error) {
+	p.ID = fmt.Sprintf("id-%d", len(s.saved)+1)
+	s.saved = append(s.saved, p)
+	return p.ID, nil
+}
+
+func TestImportSkipsHostBehindSkippedJump(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, "config")
+	config := `
+Host bastion
+    HostName bastion.example.com
+    Port 70000
+
+Host app
+    HostName app.internal
+    ProxyJump bastion
+
+Host web
+    HostName web.example.com
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		store := &memoryStore{}
+		report, err := Import(context.Background(), store, Options{Path: path, DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actions := map[string]string{}
+		for _, entry := range report.Entries {
+			actions[entry.Alias] = entry.Action
+		}
+		want := map[string]string{"bastion": ActionSkip, "app": ActionSkip, "web": ActionCreate}
+		for alias, action := range want {
+			if actions[alias] != action {
+				t.Errorf("dryRun=%v: %s action = %q, want %q", dryRun, alias, actions[alias], action)
+			}
+		}
+
+		if dryRun {
+			continue
+		}
+		if len(store.saved) != 1 || store.saved[0].Name != "web" {
+			t.Errorf("saved profiles = %+v, want only web", store.saved)
+		}
+	}
+}
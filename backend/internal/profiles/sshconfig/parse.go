@@ -0,0 +1,270 @@
+package sshconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIncludeDepth matches the recursion limit OpenSSH applies to Include.
+const maxIncludeDepth = 16
+
+var supportedKeywords = map[string]bool{
+	"host":            true,
+	"include":         true,
+	"hostname":        true,
+	"port":            true,
+	"user":            true,
+	"identityfile":    true,
+	"certificatefile": true,
+	"proxyjump":       true,
+	"proxycommand":    true,
+	"forwardagent":    true,
+}
+
+// Directive is a config line the importer does not translate.
+type Directive struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Keyword string `json:"keyword"`
+	Value   string `json:"value"`
+}
+
+type option struct {
+	keyword string
+	value   string
+}
+
+// block is a Host section. Options are kept in file order; the first value of
+// a keyword wins, as in OpenSSH.
+type block struct {
+	patterns []string
+	options  []option
+}
+
+type config struct {
+	blocks      []*block
+	unsupported []Directive
+}
+
+type parser struct {
+	home    string
+	sshDir  string
+	cfg     *config
+	current *block
+	// skipping is set inside Match blocks, whose options are ignored.
+	skipping bool
+}
+
+func parseFile(path, home string) (*config, error) {
+	p := &parser{
+		home:    home,
+		sshDir:  filepath.Join(home, ".ssh"),
+		cfg:     &config{},
+		current: &block{patterns: []string{"*"}},
+	}
+	p.cfg.blocks = append(p.cfg.blocks, p.current)
+	if err := p.parse(path, 0); err != nil {
+		return nil, err
+	}
+	return p.cfg, nil
+}
+
+func (p *parser) parse(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return errors.New("ssh config: include nested too deeply")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw, args, ok := splitLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		keyword := strings.ToLower(raw)
+
+		switch keyword {
+		case "host":
+			p.current = &block{patterns: args}
+			p.cfg.blocks = append(p.cfg.blocks, p.current)
+			p.skipping = false
+			continue
+		case "match":
+			p.cfg.unsupported = append(p.cfg.unsupported, Directive{File: path, Line: lineNum, Keyword: raw, Value: strings.Join(args, " ")})
+			p.skipping = true
+			continue
+		}
+		if p.skipping {
+			continue
+		}
+
+		if keyword == "include" {
+			// Host lines in an included file end at the end of that file.
+			current := p.current
+			if err := p.include(args, depth); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNum, err)
+			}
+			p.current, p.skipping = current, false
+			continue
+		}
+		if !supportedKeywords[keyword] {
+			p.cfg.unsupported = append(p.cfg.unsupported, Directive{File: path, Line: lineNum, Keyword: raw, Value: strings.Join(args, " ")})
+			continue
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("%s:%d: missing value for %s", path, lineNum, keyword)
+		}
+
+		value := args[0]
+		if keyword == "proxycommand" {
+			value = strings.Join(args, " ")
+		}
+		p.current.options = append(p.current.options, option{keyword: keyword, value: value})
+	}
+	return scanner.Err()
+}
+
+// include parses every file matched by the Include patterns. Relative paths
+// are resolved against ~/.ssh, and glob matches are read in lexical order.
+func (p *parser) include(patterns []string, depth int) error {
+	for _, pattern := range patterns {
+		pattern = expandTilde(pattern, p.home)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(p.sshDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if err := p.parse(match, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitLine returns the keyword and arguments of a config line.
+// Both "Keyword value" and "Keyword=value" forms are accepted, and arguments
+// may be double-quoted.
+func splitLine(line string) (string, []string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, false
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return line, nil, true
+	}
+	keyword := line[:end]
+	rest := strings.TrimLeft(line[end:], " \t")
+	if strings.HasPrefix(rest, "=") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+	return keyword, splitArgs(rest), true
+}
+
+func splitArgs(s string) []string {
+	var args []string
+	var current strings.Builder
+	inQuote := false
+	started := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			started = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// matches reports whether alias is selected by the Host patterns. A matching
+// negated pattern excludes the alias regardless of the others.
+func (b *block) matches(alias string) bool {
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if negated {
+			pattern = pattern[1:]
+		}
+		if !WildcardMatch(pattern, alias) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// WildcardMatch implements the "*" and "?" globbing of ssh_config and
+// known_hosts patterns. It ignores case; no other character is special.
+func WildcardMatch(pattern, s string) bool {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if WildcardMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return s == ""
+}
+
+func isPattern(alias string) bool {
+	return strings.ContainsAny(alias, "*?!")
+}
+
+func expandTilde(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -1,10 +1,17 @@
 package profiles
 
-import "context"
+import (
+    "context"
+    "time"
+)
 
 type Store interface {
     List(ctx context.Context) ([]Profile, error)
     Get(ctx context.Context, id string) (Profile, error)
     Save(ctx context.Context, profile Profile) (string, error)
     Delete(ctx context.Context, id string) error
+    Search(ctx context.Context, query SearchQuery) ([]Profile, error)
+    // RecordConnect bumps the connect count and last-connected time. Save
+    // leaves both untouched.
+    RecordConnect(ctx context.Context, id string, at time.Time) error
 }
@@ -1,14 +1,23 @@
 package profiles
 
 type Profile struct {
-    ID               string `json:"id"`
-    Name             string `json:"name"`
-    Group            string `json:"group"`
-    Host             string `json:"host"`
-    Port             int    `json:"port"`
-    Username         string `json:"username"`
-    AuthType         string `json:"authType"`
-    PrivateKeyPath   string `json:"privateKeyPath"`
-    UseKeyring       bool   `json:"useKeyring"`
-    KnownHostsPolicy string `json:"knownHostsPolicy"`
+    ID                   string   `json:"id"`
+    Name                 string   `json:"name"`
+    Group                string   `json:"group"`
+    Host                 string   `json:"host"`
+    Port                 int      `json:"port"`
+    Username             string   `json:"username"`
+    AuthType             string   `json:"authType"`
+    PrivateKeyPath       string   `json:"privateKeyPath"`
+    CertificatePath      string   `json:"certificatePath"`
+    UseKeyring           bool     `json:"useKeyring"`
+    KnownHostsPolicy     string   `json:"knownHostsPolicy"`
+    JumpProfileIDs       []string `json:"jumpProfileIds"`
+    ProxyCommand         string   `json:"proxyCommand"`
+    ReconnectMaxAttempts int      `json:"reconnectMaxAttempts"`
+    ForwardAgent         bool     `json:"forwardAgent"`
+    Tags                 []string `json:"tags"`
+    Favorite             bool     `json:"favorite"`
+    LastConnectedAt      int64    `json:"lastConnectedAt"`
+    ConnectCount         int      `json:"connectCount"`
 }
@@ -0,0 +1,234 @@
+package hostkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/common"
+)
+
+const (
+	markerCertAuthority = "cert-authority"
+	markerRevoked       = "revoked"
+)
+
+// Authority is an @cert-authority line trusted to sign host certificates for
+// hosts matching Pattern.
+type Authority struct {
+	Pattern     string `json:"pattern"`
+	KeyType     string `json:"keyType"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment"`
+}
+
+type markerEntry struct {
+	hosts string
+	key   ssh.PublicKey
+}
+
+// markers holds the @cert-authority and @revoked lines of a known_hosts file.
+type markers struct {
+	authorities []markerEntry
+	revoked     []ssh.PublicKey
+}
+
+func loadMarkers(path string) (*markers, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &markers{}
+	for _, line := range lines {
+		marker, hosts, key, _, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		switch marker {
+		case markerCertAuthority:
+			set.authorities = append(set.authorities, markerEntry{hosts: strings.Join(hosts, ","), key: key})
+		case markerRevoked:
+			set.revoked = append(set.revoked, key)
+		}
+	}
+	return set, nil
+}
+
+// isAuthority matches the ssh.CertChecker IsHostAuthority signature.
+func (s *markers) isAuthority(auth ssh.PublicKey, address string) bool {
+	if s.isRevokedKey(auth) {
+		return false
+	}
+	for _, entry := range s.authorities {
+		if keysEqual(entry.key, auth) && matchHosts(entry.hosts, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRevoked matches the ssh.CertChecker IsRevoked signature. A certificate is
+// revoked when either its key or its signing CA is listed.
+func (s *markers) isRevoked(cert *ssh.Certificate) bool {
+	return s.isRevokedKey(cert.Key) || s.isRevokedKey(cert.SignatureKey)
+}
+
+func (s *markers) isRevokedKey(key ssh.PublicKey) bool {
+	for _, revoked := range s.revoked {
+		if keysEqual(revoked, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAuthorities returns the host CAs trusted in the known_hosts file at path.
+func ListAuthorities(path string) ([]Authority, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Authority{}
+	for _, line := range lines {
+		marker, hosts, key, comment, ok := parseLine(line)
+		if !ok || marker != markerCertAuthority {
+			continue
+		}
+		items = append(items, Authority{
+			Pattern:     strings.Join(hosts, ","),
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     comment,
+		})
+	}
+	return items, nil
+}
+
+// AddAuthority trusts the CA public key, given in authorized_keys format, to
+// sign host certificates for hosts matching pattern. Adding an existing
+// pattern/key pair is a no-op.
+func AddAuthority(path, pattern, publicKey string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.ContainsAny(pattern, " \t") {
+		return errors.New("invalid host pattern")
+	}
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return fmt.Errorf("parse CA key: %w", err)
+	}
+	if _, ok := key.(*ssh.Certificate); ok {
+		return errors.New("CA key must be a plain public key")
+	}
+
+	return rewriteLines(path, func(lines []string) ([]string, error) {
+		for _, line := range lines {
+			marker, hosts, existing, _, ok := parseLine(line)
+			if ok && marker == markerCertAuthority && strings.Join(hosts, ",") == pattern && keysEqual(existing, key) {
+				return lines, nil
+			}
+		}
+		line := "@" + markerCertAuthority + " " + pattern + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+		if comment != "" {
+			line += " " + comment
+		}
+		return append(lines, line), nil
+	})
+}
+
+// RemoveAuthority drops the CA with the given fingerprint from pattern.
+func RemoveAuthority(path, pattern, fingerprint string) error {
+	return rewriteLines(path, func(lines []string) ([]string, error) {
+		kept := make([]string, 0, len(lines))
+		removed := false
+		for _, line := range lines {
+			marker, hosts, key, _, ok := parseLine(line)
+			if ok && marker == markerCertAuthority && strings.Join(hosts, ",") == pattern && ssh.FingerprintSHA256(key) == fingerprint {
+				removed = true
+				continue
+			}
+			kept = append(kept, line)
+		}
+		if !removed {
+			return nil, common.ErrNotFound
+		}
+		return kept, nil
+	})
+}
+
+func parseLine(line string) (marker string, hosts []string, key ssh.PublicKey, comment string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", nil, nil, "", false
+	}
+	marker, hosts, key, comment, _, err := ssh.ParseKnownHosts([]byte(trimmed))
+	if err != nil {
+		return "", nil, nil, "", false
+	}
+	return marker, hosts, key, comment, true
+}
+
+func keysEqual(a, b ssh.PublicKey) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// rewriteLines applies edit to the lines of the file at path and replaces the
+// file atomically so concurrent readers never see a partial write.
+func rewriteLines(path string, edit func([]string) ([]string, error)) error {
+	if err := ensureFile(path); err != nil {
+		return err
+	}
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+	lines, err = edit(lines)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".known_hosts-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
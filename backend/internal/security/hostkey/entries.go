@@ -0,0 +1,202 @@
+package hostkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"goterm/backend/internal/common"
+)
+
+var ErrEntryChanged = errors.New("known_hosts entry changed")
+
+// Entry is one key line of a known_hosts file. Line is 1-based and, together
+// with Fingerprint, identifies the entry for delete and replace.
+type Entry struct {
+	Line        int      `json:"line"`
+	Marker      string   `json:"marker"`
+	Hosts       []string `json:"hosts"`
+	Hashed      bool     `json:"hashed"`
+	KeyType     string   `json:"keyType"`
+	Fingerprint string   `json:"fingerprint"`
+	Comment     string   `json:"comment"`
+}
+
+// ListEntries returns the entries of the known_hosts file at path. When host
+// is set only entries matching it are returned, including hashed ones.
+func ListEntries(path, host string) ([]Entry, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Entry{}
+	for i, line := range lines {
+		marker, hosts, key, comment, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		if host != "" && !matchHosts(strings.Join(hosts, ","), host) {
+			continue
+		}
+		items = append(items, Entry{
+			Line:        i + 1,
+			Marker:      marker,
+			Hosts:       hosts,
+			Hashed:      isHashed(hosts),
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     comment,
+		})
+	}
+	return items, nil
+}
+
+// DeleteEntry removes the entry at line. The fingerprint must still match so
+// an outdated listing never deletes the wrong line.
+func DeleteEntry(path string, line int, fingerprint string) error {
+	return rewriteLines(path, func(lines []string) ([]string, error) {
+		if err := checkEntry(lines, line, fingerprint); err != nil {
+			return nil, err
+		}
+		return append(lines[:line-1:line-1], lines[line:]...), nil
+	})
+}
+
+// ReplaceEntry swaps the key of the entry at line for publicKey, given in
+// authorized_keys format, keeping its host patterns and marker.
+func ReplaceEntry(path string, line int, fingerprint, publicKey string) error {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return fmt.Errorf("parse host key: %w", err)
+	}
+
+	return rewriteLines(path, func(lines []string) ([]string, error) {
+		if err := checkEntry(lines, line, fingerprint); err != nil {
+			return nil, err
+		}
+		marker, hosts, _, _, _ := parseLine(lines[line-1])
+		replaced := knownhosts.Line(hosts, key)
+		if marker != "" {
+			replaced = "@" + marker + " " + replaced
+		}
+		lines[line-1] = replaced
+		return lines, nil
+	})
+}
+
+// replaceHostKey drops the stale lines reported by a knownhosts.KeyError and
+// records key for hostname. The new line is hashed when any of the stale ones
+// were.
+func replaceHostKey(path, hostname string, key ssh.PublicKey, stale []knownhosts.KnownKey) error {
+	return rewriteLines(path, func(lines []string) ([]string, error) {
+		drop := map[int]bool{}
+		hashed := false
+		for _, known := range stale {
+			if known.Line < 1 || known.Line > len(lines) {
+				continue
+			}
+			drop[known.Line] = true
+			if _, hosts, _, _, ok := parseLine(lines[known.Line-1]); ok && isHashed(hosts) {
+				hashed = true
+			}
+		}
+
+		kept := make([]string, 0, len(lines)+1)
+		for i, line := range lines {
+			if !drop[i+1] {
+				kept = append(kept, line)
+			}
+		}
+
+		host := knownhosts.Normalize(hostname)
+		if hashed {
+			host = knownhosts.HashHostname(host)
+		}
+		return append(kept, knownhosts.Line([]string{host}, key)), nil
+	})
+}
+
+func checkEntry(lines []string, line int, fingerprint string) error {
+	if line < 1 || line > len(lines) {
+		return common.ErrNotFound
+	}
+	_, _, key, _, ok := parseLine(lines[line-1])
+	if !ok {
+		return common.ErrNotFound
+	}
+	if ssh.FingerprintSHA256(key) != fingerprint {
+		return ErrEntryChanged
+	}
+	return nil
+}
+
+func isHashed(hosts []string) bool {
+	for _, host := range hosts {
+		if strings.HasPrefix(host, "|1|") {
+			return true
+		}
+	}
+	return false
+}
+
+// HostLines returns the raw lines, markers included, whose host patterns match
+// any of hosts, given as host or host:port.
+func HostLines(path string, hosts []string) ([]string, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, line := range lines {
+		_, patterns, _, _, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		field := strings.Join(patterns, ",")
+		for _, host := range hosts {
+			if matchHosts(field, host) {
+				matched = append(matched, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// MergeLines appends the known_hosts lines that are not present yet, compared
+// by marker, host patterns and key, and returns how many were added.
+func MergeLines(path string, incoming []string) (int, error) {
+	added := 0
+	err := rewriteLines(path, func(lines []string) ([]string, error) {
+		seen := map[string]bool{}
+		for _, line := range lines {
+			if marker, hosts, key, _, ok := parseLine(line); ok {
+				seen[lineIdentity(marker, hosts, key)] = true
+			}
+		}
+		for _, line := range incoming {
+			marker, hosts, key, _, ok := parseLine(line)
+			if !ok {
+				return nil, fmt.Errorf("invalid known_hosts line: %q", line)
+			}
+			id := lineIdentity(marker, hosts, key)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			lines = append(lines, strings.TrimSpace(line))
+			added++
+		}
+		return lines, nil
+	})
+	return added, err
+}
+
+func lineIdentity(marker string, hosts []string, key ssh.PublicKey) string {
+	return marker + " " + strings.Join(hosts, ",") + " " + string(key.Marshal())
+}
@@ -0,0 +1,72 @@
+package hostkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestListEntriesFiltersByHostAndPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	lines := []string{
+		knownhosts.Line([]string{"web.example.com"}, newHostKey(t)),
+		knownhosts.Line([]string{"[web.example.com]:2222"}, newHostKey(t)),
+		knownhosts.Line([]string{"[*.example.com]:2200"}, newHostKey(t)),
+		knownhosts.Line([]string{knownhosts.HashHostname("[db.example.com]:2222")}, newHostKey(t)),
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host  string
+		lines []int
+	}{
+		{"web.example.com", []int{1}},
+		{"web.example.com:2222", []int{2}},
+		{"[web.example.com]:2222", []int{2}},
+		{"api.example.com:2200", []int{3}},
+		{"db.example.com:2222", []int{4}},
+		{"db.example.com", nil},
+		{"", []int{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		entries, err := ListEntries(path, tt.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, entry := range entries {
+			got = append(got, entry.Line)
+		}
+		if len(got) != len(tt.lines) {
+			t.Errorf("ListEntries(%q) lines = %v, want %v", tt.host, got, tt.lines)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.lines[i] {
+				t.Errorf("ListEntries(%q) lines = %v, want %v", tt.host, got, tt.lines)
+				break
+			}
+		}
+	}
+}
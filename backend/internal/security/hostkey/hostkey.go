@@ -22,10 +22,15 @@ var ErrHostKeyRejected = errors.New("host key rejected")
 
 type PromptFunc func(host string, key ssh.PublicKey, fingerprint string) (bool, error)
 
+// ChangedFunc asks whether the recorded keys of host, identified by their
+// previous fingerprints, should be replaced by key.
+type ChangedFunc func(host string, key ssh.PublicKey, fingerprint string, previous []string) (bool, error)
+
 type Verifier struct {
-    Path   string
-    Policy Policy
-    Prompt PromptFunc
+    Path    string
+    Policy  Policy
+    Prompt  PromptFunc
+    Changed ChangedFunc
 }
 
 func (v *Verifier) Callback() ssh.HostKeyCallback {
@@ -34,6 +39,24 @@ func (v *Verifier) Callback() ssh.HostKeyCallback {
             return err
         }
 
+        trusted, err := loadMarkers(v.Path)
+        if err != nil {
+            return err
+        }
+
+        if cert, ok := key.(*ssh.Certificate); ok && cert.CertType == ssh.HostCert {
+            if trusted.isAuthority(cert.SignatureKey, hostname) {
+                checker := &ssh.CertChecker{
+                    IsHostAuthority: trusted.isAuthority,
+                    IsRevoked:       trusted.isRevoked,
+                }
+                return checker.CheckHostKey(hostname, remote, key)
+            }
+            // No trusted CA for this host: verify the certified key like a
+            // plain host key.
+            key = cert.Key
+        }
+
         callback, err := knownhosts.New(v.Path)
         if err != nil {
             return err
@@ -43,7 +66,7 @@ func (v *Verifier) Callback() ssh.HostKeyCallback {
             return nil
         } else if keyErr, ok := err.(*knownhosts.KeyError); ok {
             if len(keyErr.Want) > 0 {
-                return err
+                return v.changed(hostname, key, keyErr)
             }
 
             switch v.Policy {
@@ -72,6 +95,28 @@ func (v *Verifier) Callback() ssh.HostKeyCallback {
     }
 }
 
+// changed handles a host whose key no longer matches known_hosts. Only the ask
+// policy offers to replace the entry; every other outcome keeps the error.
+func (v *Verifier) changed(hostname string, key ssh.PublicKey, keyErr *knownhosts.KeyError) error {
+    if v.Policy != PolicyAsk || v.Changed == nil {
+        return keyErr
+    }
+
+    previous := make([]string, 0, len(keyErr.Want))
+    for _, known := range keyErr.Want {
+        previous = append(previous, ssh.FingerprintSHA256(known.Key))
+    }
+
+    replace, err := v.Changed(hostname, key, ssh.FingerprintSHA256(key), previous)
+    if err != nil {
+        return err
+    }
+    if !replace {
+        return keyErr
+    }
+    return replaceHostKey(v.Path, hostname, key, keyErr.Want)
+}
+
 func ensureFile(path string) error {
     if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
         return err
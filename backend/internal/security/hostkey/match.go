@@ -0,0 +1,64 @@
+package hostkey
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strings"
+
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"goterm/backend/internal/profiles/sshconfig"
+)
+
+// matchHosts reports whether the comma-separated known_hosts host field
+// matches address. It follows OpenSSH: "*" and "?" wildcards, "!" negation
+// that vetoes the whole field, "[host]:port" for non-standard ports and
+// "|1|salt|hash" hashed entries.
+func matchHosts(field, address string) bool {
+	target := knownhosts.Normalize(address)
+	matched := false
+	for _, pattern := range strings.Split(field, ",") {
+		if pattern == "" {
+			continue
+		}
+		negated := strings.HasPrefix(pattern, "!")
+		if negated {
+			pattern = pattern[1:]
+		}
+		if !matchHostPattern(pattern, target) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func matchHostPattern(pattern, target string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		return matchHashed(pattern, target)
+	}
+	// Brackets in "[host]:port" are literal, so path.Match cannot be used.
+	return sshconfig.WildcardMatch(pattern, target)
+}
+
+func matchHashed(pattern, target string) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(target))
+	return hmac.Equal(mac.Sum(nil), want)
+}
@@ -0,0 +1,57 @@
+package hostkey
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"testing"
+)
+
+func TestMatchHosts(t *testing.T) {
+	tests := []struct {
+		field   string
+		address string
+		want    bool
+	}{
+		{"web.example.com", "web.example.com:22", true},
+		{"web.example.com", "WEB.example.com:22", true},
+		{"web.example.com", "web.example.com:2222", false},
+		{"[web.example.com]:2222", "web.example.com:2222", true},
+		{"[web.example.com]:2222", "[web.example.com]:2222", true},
+		{"[web.example.com]:2222", "web.example.com:2200", false},
+		{"[web.example.com]:2222", "web.example.com:22", false},
+		{"[10.0.0.1]:2200", "10.0.0.1:2200", true},
+		{"[::1]:2222", "[::1]:2222", true},
+		{"*.example.com", "db.example.com:22", true},
+		{"*.example.com", "example.com:22", false},
+		{"[*.example.com]:2222", "db.example.com:2222", true},
+		{"[*.example.com]:2222", "db.example.com:22", false},
+		{"db?.example.com", "db1.example.com:22", true},
+		{"db?.example.com", "db10.example.com:22", false},
+		{"*.example.com,!db.example.com", "web.example.com:22", true},
+		{"*.example.com,!db.example.com", "db.example.com:22", false},
+		{"!db.example.com,*.example.com", "db.example.com:22", false},
+		{"[*.example.com]:2222,![db.example.com]:2222", "db.example.com:2222", false},
+		{"!db.example.com", "web.example.com:22", false},
+		{"web.example.com,,10.0.0.5", "10.0.0.5:22", true},
+	}
+	for _, tt := range tests {
+		if got := matchHosts(tt.field, tt.address); got != tt.want {
+			t.Errorf("matchHosts(%q, %q) = %v, want %v", tt.field, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestMatchHostsHashed(t *testing.T) {
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte("[web.example.com]:2222"))
+	field := "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if !matchHosts(field, "web.example.com:2222") {
+		t.Error("hashed entry does not match its host")
+	}
+	if matchHosts(field, "web.example.com:22") {
+		t.Error("hashed entry matches another port")
+	}
+}
@@ -0,0 +1,112 @@
+package keyring
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	BackendSystem = "system"
+	BackendVault  = "vault"
+)
+
+// Backend stores secrets by key. Get returns ErrNotFound for missing keys and
+// Delete treats them as already deleted.
+type Backend interface {
+	Name() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend = System{}
+)
+
+// SetBackend switches where the package-level helpers read and write secrets.
+func SetBackend(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backend = b
+}
+
+func ActiveBackend() Backend {
+	return active()
+}
+
+func active() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	return backend
+}
+
+// System is the OS credential store: Keychain, Credential Manager or Secret
+// Service.
+type System struct{}
+
+func (System) Name() string {
+	return BackendSystem
+}
+
+func (System) Get(key string) (string, error) {
+	return keyring.Get(serviceName, key)
+}
+
+func (System) Set(key, value string) error {
+	return keyring.Set(serviceName, key, value)
+}
+
+func (System) Delete(key string) error {
+	if err := keyring.Delete(serviceName, key); err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// SystemAvailable reports whether the OS credential store answers at all.
+func SystemAvailable() bool {
+	_, err := keyring.Get(serviceName, "availability-probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// SecretKeys lists every key the helpers may use for the given profiles.
+func SecretKeys(profileIDs, mysqlProfileIDs []string) []string {
+	keys := make([]string, 0, 2*len(profileIDs)+len(mysqlProfileIDs))
+	for _, id := range profileIDs {
+		keys = append(keys, passwordKey(id), passphraseKey(id))
+	}
+	for _, id := range mysqlProfileIDs {
+		keys = append(keys, mysqlPasswordKey(id))
+	}
+	return keys
+}
+
+// Migrate moves keys from one backend to another. Each secret is deleted from
+// the source only after it was written to the destination, so an interrupted
+// migration can simply be run again. It returns the number of secrets moved.
+func Migrate(from, to Backend, keys []string) (int, error) {
+	moved := 0
+	for _, key := range keys {
+		value, err := from.Get(key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return moved, err
+		}
+		if err := to.Set(key, value); err != nil {
+			return moved, err
+		}
+		if err := from.Delete(key); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -1,8 +1,6 @@
 package keyring
 
 import (
-	"errors"
-
 	"github.com/zalando/go-keyring"
 )
 
@@ -23,55 +21,37 @@ func mysqlPasswordKey(profileID string) string {
 }
 
 func SetPassword(profileID, password string) error {
-	return keyring.Set(serviceName, passwordKey(profileID), password)
+	return active().Set(passwordKey(profileID), password)
 }
 
 func GetPassword(profileID string) (string, error) {
-	return keyring.Get(serviceName, passwordKey(profileID))
+	return active().Get(passwordKey(profileID))
 }
 
 func DeletePassword(profileID string) error {
-	if err := keyring.Delete(serviceName, passwordKey(profileID)); err != nil {
-		if errors.Is(err, keyring.ErrNotFound) {
-			return nil
-		}
-		return err
-	}
-	return nil
+	return active().Delete(passwordKey(profileID))
 }
 
 func SetPrivateKeyPassphrase(profileID, passphrase string) error {
-	return keyring.Set(serviceName, passphraseKey(profileID), passphrase)
+	return active().Set(passphraseKey(profileID), passphrase)
 }
 
 func GetPrivateKeyPassphrase(profileID string) (string, error) {
-	return keyring.Get(serviceName, passphraseKey(profileID))
+	return active().Get(passphraseKey(profileID))
 }
 
 func DeletePrivateKeyPassphrase(profileID string) error {
-	if err := keyring.Delete(serviceName, passphraseKey(profileID)); err != nil {
-		if errors.Is(err, keyring.ErrNotFound) {
-			return nil
-		}
-		return err
-	}
-	return nil
+	return active().Delete(passphraseKey(profileID))
 }
 
 func SetMySQLPassword(profileID, password string) error {
-	return keyring.Set(serviceName, mysqlPasswordKey(profileID), password)
+	return active().Set(mysqlPasswordKey(profileID), password)
 }
 
 func GetMySQLPassword(profileID string) (string, error) {
-	return keyring.Get(serviceName, mysqlPasswordKey(profileID))
+	return active().Get(mysqlPasswordKey(profileID))
 }
 
 func DeleteMySQLPassword(profileID string) error {
-	if err := keyring.Delete(serviceName, mysqlPasswordKey(profileID)); err != nil {
-		if errors.Is(err, keyring.ErrNotFound) {
-			return nil
-		}
-		return err
-	}
-	return nil
+	return active().Delete(mysqlPasswordKey(profileID))
 }
@@ -0,0 +1,347 @@
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"goterm/backend/internal/security/secretbox"
+)
+
+const (
+	vaultVersion       = 1
+	vaultCheck         = "goterm-vault"
+	defaultIdleTimeout = 15 * time.Minute
+)
+
+var ErrLocked = errors.New("vault is locked")
+
+// vaultFile is the on-disk form. Every entry has its own nonce and is bound to
+// its key name as additional data, so entries cannot be swapped around.
+type vaultFile struct {
+	Version int                      `json:"version"`
+	Params  secretbox.Params         `json:"params"`
+	Check   secretbox.Box            `json:"check"`
+	Entries map[string]secretbox.Box `json:"entries"`
+}
+
+type VaultStatus struct {
+	Active             bool `json:"active"`
+	Exists             bool `json:"exists"`
+	Locked             bool `json:"locked"`
+	IdleTimeoutSeconds int  `json:"idleTimeoutSeconds"`
+}
+
+// Vault is an encrypted file of secrets for machines without a usable OS
+// credential store. The key is derived from a master password on Unlock and
+// dropped again by Lock or after the idle timeout.
+type Vault struct {
+	path string
+
+	mu       sync.Mutex
+	key      []byte
+	file     *vaultFile
+	idle     time.Duration
+	timer    *time.Timer
+	lastUsed time.Time
+	onLock   func()
+}
+
+func NewVault(path string) *Vault {
+	return &Vault{path: path, idle: defaultIdleTimeout}
+}
+
+func (v *Vault) Name() string {
+	return BackendVault
+}
+
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+func (v *Vault) Status() VaultStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return VaultStatus{
+		Active:             active() == Backend(v),
+		Exists:             v.Exists(),
+		Locked:             v.key == nil,
+		IdleTimeoutSeconds: int(v.idle / time.Second),
+	}
+}
+
+// OnLock registers fn to run whenever the vault locks, including on idle.
+func (v *Vault) OnLock(fn func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onLock = fn
+}
+
+// SetIdleTimeout changes how long the vault stays unlocked without use. Zero
+// disables auto-lock.
+func (v *Vault) SetIdleTimeout(d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.idle = d
+	if v.key != nil {
+		v.touchLocked()
+	}
+}
+
+// Unlock derives the key from password. When no vault file exists yet, a new
+// empty vault protected by password is created.
+func (v *Vault) Unlock(password string) error {
+	if password == "" {
+		return errors.New("master password is required")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	file, err := v.read()
+	if err != nil {
+		return err
+	}
+
+	if file == nil {
+		params, err := secretbox.NewParams()
+		if err != nil {
+			return err
+		}
+		key, err := params.Key(password)
+		if err != nil {
+			return err
+		}
+		check, err := secretbox.SealKey(key, []byte(vaultCheck), nil)
+		if err != nil {
+			return err
+		}
+		file = &vaultFile{
+			Version: vaultVersion,
+			Params:  params,
+			Check:   check,
+			Entries: map[string]secretbox.Box{},
+		}
+		if err := v.write(file); err != nil {
+			return err
+		}
+		v.key, v.file = key, file
+		v.touchLocked()
+		return nil
+	}
+
+	key, err := file.Params.Key(password)
+	if err != nil {
+		return err
+	}
+	if _, err := secretbox.OpenKey(key, file.Check, nil); err != nil {
+		return errors.New("wrong master password")
+	}
+	v.key, v.file = key, file
+	v.touchLocked()
+	return nil
+}
+
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	fn := v.lockLocked()
+	v.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+func (v *Vault) Get(key string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	v.touchLocked()
+
+	box, ok := v.file.Entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	value, err := secretbox.OpenKey(v.key, box, []byte(key))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func (v *Vault) Set(key, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touchLocked()
+
+	box, err := secretbox.SealKey(v.key, []byte(value), []byte(key))
+	if err != nil {
+		return err
+	}
+	v.file.Entries[key] = box
+	return v.write(v.file)
+}
+
+func (v *Vault) Delete(key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touchLocked()
+
+	if _, ok := v.file.Entries[key]; !ok {
+		return nil
+	}
+	delete(v.file.Entries, key)
+	return v.write(v.file)
+}
+
+// Keys lists the stored key names. Names are not secret and can be read while
+// the vault is locked.
+func (v *Vault) Keys() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	file := v.file
+	if file == nil {
+		var err error
+		if file, err = v.read(); err != nil || file == nil {
+			return nil, err
+		}
+	}
+	keys := make([]string, 0, len(file.Entries))
+	for key := range file.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Remove locks the vault and deletes its file.
+func (v *Vault) Remove() error {
+	v.mu.Lock()
+	fn := v.lockLocked()
+	err := os.Remove(v.path)
+	v.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// lockLocked wipes the key and returns the lock callback to run once the
+// mutex is released.
+func (v *Vault) lockLocked() func() {
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+	if v.key == nil {
+		return nil
+	}
+	for i := range v.key {
+		v.key[i] = 0
+	}
+	v.key, v.file = nil, nil
+	return v.onLock
+}
+
+func (v *Vault) touchLocked() {
+	v.lastUsed = time.Now()
+	if v.idle <= 0 {
+		if v.timer != nil {
+			v.timer.Stop()
+			v.timer = nil
+		}
+		return
+	}
+	if v.timer == nil {
+		v.timer = time.AfterFunc(v.idle, v.expire)
+		return
+	}
+	v.timer.Reset(v.idle)
+}
+
+// expire locks the vault once it has been idle long enough. A timer that
+// fired while another call was resetting it finds recent use and re-arms.
+func (v *Vault) expire() {
+	v.mu.Lock()
+	if v.key == nil || v.timer == nil {
+		v.mu.Unlock()
+		return
+	}
+	if remaining := v.idle - time.Since(v.lastUsed); remaining > 0 {
+		v.timer.Reset(remaining)
+		v.mu.Unlock()
+		return
+	}
+	fn := v.lockLocked()
+	v.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+func (v *Vault) read() (*vaultFile, error) {
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version != vaultVersion {
+		return nil, errors.New("unsupported vault version")
+	}
+	if file.Entries == nil {
+		file.Entries = map[string]secretbox.Box{}
+	}
+	return &file, nil
+}
+
+// write replaces the vault file atomically.
+func (v *Vault) write(file *vaultFile) error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), ".vault-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), v.path)
+}
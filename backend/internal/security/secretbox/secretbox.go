@@ -0,0 +1,134 @@
+package secretbox
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	kdfArgon2id = "argon2id"
+
+	defaultTime    = 3
+	defaultMemory  = 64 * 1024
+	defaultThreads = 4
+	saltSize       = 16
+
+	maxTime   = 16
+	maxMemory = 1024 * 1024
+)
+
+var ErrDecrypt = errors.New("wrong passphrase or corrupted data")
+
+// Params are the Argon2id settings used to derive a key from a passphrase.
+// They travel with the data so they can be raised later without breaking old
+// blobs.
+type Params struct {
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+}
+
+// Box is one XChaCha20-Poly1305 ciphertext with its nonce.
+type Box struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Sealed is data encrypted under a key derived from a passphrase.
+type Sealed struct {
+	Params
+	Box
+}
+
+// NewParams returns the default parameters with a fresh salt.
+func NewParams() (Params, error) {
+	params := Params{
+		KDF:     kdfArgon2id,
+		Time:    defaultTime,
+		Memory:  defaultMemory,
+		Threads: defaultThreads,
+		Salt:    make([]byte, saltSize),
+	}
+	if _, err := rand.Read(params.Salt); err != nil {
+		return Params{}, err
+	}
+	return params, nil
+}
+
+// Key derives the encryption key for passphrase.
+func (p Params) Key(passphrase string) ([]byte, error) {
+	if p.KDF != kdfArgon2id {
+		return nil, errors.New("unsupported key derivation: " + p.KDF)
+	}
+	// Params may come from other machines; refuse values that would stall or
+	// exhaust memory before the passphrase is even checked.
+	if p.Time == 0 || p.Time > maxTime || p.Memory > maxMemory || p.Threads == 0 {
+		return nil, errors.New("unsupported key derivation parameters")
+	}
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, chacha20poly1305.KeySize), nil
+}
+
+// SealKey encrypts plaintext under key with a random nonce. additional is
+// authenticated but not encrypted; the same value must be passed to OpenKey.
+func SealKey(key, plaintext, additional []byte) (Box, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return Box{}, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return Box{}, err
+	}
+	return Box{Nonce: nonce, Ciphertext: aead.Seal(nil, nonce, plaintext, additional)}, nil
+}
+
+func OpenKey(key []byte, box Box, additional []byte) ([]byte, error) {
+	if len(box.Nonce) != chacha20poly1305.NonceSizeX {
+		return nil, ErrDecrypt
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, box.Nonce, box.Ciphertext, additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func Seal(passphrase string, plaintext []byte) (*Sealed, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required")
+	}
+
+	params, err := NewParams()
+	if err != nil {
+		return nil, err
+	}
+	key, err := params.Key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	box, err := SealKey(key, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{Params: params, Box: box}, nil
+}
+
+func Open(passphrase string, sealed *Sealed) ([]byte, error) {
+	if sealed == nil {
+		return nil, errors.New("no sealed data")
+	}
+	key, err := sealed.Key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return OpenKey(key, sealed.Box, nil)
+}
@@ -0,0 +1,177 @@
+package sshagent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"goterm/backend/internal/security/keyring"
+)
+
+const (
+	SourceSystem  = "system"
+	SourceBuiltin = "builtin"
+)
+
+type Key struct {
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment"`
+	Source      string `json:"source"`
+}
+
+// Agent uses the ssh-agent behind SSH_AUTH_SOCK when one is reachable and an
+// in-process keyring otherwise.
+type Agent struct {
+	builtin agent.Agent
+
+	mu     sync.Mutex
+	conn   net.Conn
+	system agent.ExtendedAgent
+}
+
+func New() *Agent {
+	return &Agent{builtin: agent.NewKeyring()}
+}
+
+// HasSystem reports whether a system agent is reachable.
+func (a *Agent) HasSystem() bool {
+	_, ok := a.systemAgent()
+	return ok
+}
+
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	if system, ok := a.systemAgent(); ok {
+		signers, err := system.Signers()
+		if err == nil {
+			return signers, nil
+		}
+		a.resetSystem()
+	}
+	return a.builtin.Signers()
+}
+
+func (a *Agent) Keys() ([]Key, error) {
+	ag, source := agent.Agent(a.builtin), SourceBuiltin
+	if system, ok := a.systemAgent(); ok {
+		ag, source = system, SourceSystem
+	}
+
+	keys, err := ag.List()
+	if err != nil {
+		if source == SourceSystem {
+			a.resetSystem()
+		}
+		return nil, err
+	}
+
+	items := make([]Key, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, Key{
+			Type:        key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     key.Comment,
+			Source:      source,
+		})
+	}
+	return items, nil
+}
+
+// LoadKey adds the private key at keyPath to the in-process agent unless it
+// holds that key already. Encrypted keys are unlocked with the passphrase
+// stored for profileID.
+func (a *Agent) LoadKey(profileID, keyPath, comment string) error {
+	keyBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	key, err := ssh.ParseRawPrivateKey(keyBytes)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if !errors.As(err, &missing) {
+			return err
+		}
+		passphrase, passErr := keyring.GetPrivateKeyPassphrase(profileID)
+		if passErr != nil {
+			return err
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+		if err != nil {
+			return err
+		}
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return err
+	}
+	loaded, err := a.hasBuiltin(ssh.FingerprintSHA256(signer.PublicKey()))
+	if err != nil || loaded {
+		return err
+	}
+
+	return a.builtin.Add(agent.AddedKey{PrivateKey: key, Comment: comment})
+}
+
+// hasBuiltin reports whether the in-process agent holds the key with the
+// given SHA256 fingerprint.
+func (a *Agent) hasBuiltin(fingerprint string) (bool, error) {
+	keys, err := a.builtin.List()
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if ssh.FingerprintSHA256(key) == fingerprint {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RemoveAll drops every key from the in-process agent.
+func (a *Agent) RemoveAll() error {
+	return a.builtin.RemoveAll()
+}
+
+// Forward serves agent requests from the remote side of client. It must be
+// called once per client, before sessions request forwarding.
+func (a *Agent) Forward(client *ssh.Client) error {
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" && a.HasSystem() {
+		return agent.ForwardToRemote(client, sock)
+	}
+	return agent.ForwardToAgent(client, a.builtin)
+}
+
+func (a *Agent) systemAgent() (agent.ExtendedAgent, bool) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.system == nil {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, false
+		}
+		a.conn = conn
+		a.system = agent.NewClient(conn)
+	}
+	return a.system, true
+}
+
+func (a *Agent) resetSystem() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn != nil {
+		_ = a.conn.Close()
+	}
+	a.conn = nil
+	a.system = nil
+}
@@ -0,0 +1,54 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func writeKey(t *testing.T, dir, name string) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKeyAddsEachKeyOnce(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := t.TempDir()
+	first := writeKey(t, dir, "id_first")
+	second := writeKey(t, dir, "id_second")
+
+	a := New()
+	for _, path := range []string{first, first, second, first} {
+		if err := a.LoadKey("", path, filepath.Base(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := a.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("agent holds %d keys, want 2: %+v", len(keys), keys)
+	}
+	if keys[0].Comment != "id_first" || keys[1].Comment != "id_second" {
+		t.Fatalf("unexpected keys: %+v", keys)
+	}
+}
@@ -0,0 +1,101 @@
+package sshkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/profiles"
+)
+
+// Sessions is the part of the session manager a deploy needs.
+type Sessions interface {
+	ProfileID(sessionID string) (string, error)
+	VerifyKey(ctx context.Context, profileID, keyPath string) error
+}
+
+// Installer appends a public key to the remote authorized_keys.
+type Installer interface {
+	AppendAuthorizedKey(sessionID, publicKey string) (bool, error)
+}
+
+type DeployOptions struct {
+	// KeyPath is the private key; its public half is read from KeyPath.pub.
+	KeyPath string `json:"keyPath"`
+	// SwitchAuth moves the profile over to key authentication once the key
+	// was verified.
+	SwitchAuth bool `json:"switchAuth"`
+}
+
+type DeployResult struct {
+	Added    bool `json:"added"`
+	Verified bool `json:"verified"`
+	Switched bool `json:"switched"`
+}
+
+// Deployer installs public keys on hosts through an already connected session,
+// like ssh-copy-id.
+type Deployer struct {
+	sessions  Sessions
+	installer Installer
+	store     profiles.Store
+}
+
+func NewDeployer(sessions Sessions, installer Installer, store profiles.Store) *Deployer {
+	return &Deployer{sessions: sessions, installer: installer, store: store}
+}
+
+func (d *Deployer) Deploy(ctx context.Context, sessionID string, opts DeployOptions) (DeployResult, error) {
+	var result DeployResult
+	if opts.KeyPath == "" {
+		return result, errors.New("key path is required")
+	}
+	publicKey, err := readPublicKey(opts.KeyPath + ".pub")
+	if err != nil {
+		return result, err
+	}
+	profileID, err := d.sessions.ProfileID(sessionID)
+	if err != nil {
+		return result, err
+	}
+
+	result.Added, err = d.installer.AppendAuthorizedKey(sessionID, publicKey)
+	if err != nil {
+		return result, fmt.Errorf("install key: %w", err)
+	}
+	if err := d.sessions.VerifyKey(ctx, profileID, opts.KeyPath); err != nil {
+		return result, fmt.Errorf("verify key: %w", err)
+	}
+	result.Verified = true
+
+	if !opts.SwitchAuth {
+		return result, nil
+	}
+	profile, err := d.store.Get(ctx, profileID)
+	if err != nil {
+		return result, err
+	}
+	profile.AuthType = "privateKey"
+	profile.PrivateKeyPath = opts.KeyPath
+	profile.CertificatePath = ""
+	if _, err := d.store.Save(ctx, profile); err != nil {
+		return result, err
+	}
+	result.Switched = true
+	return result, nil
+}
+
+func readPublicKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey(data); err != nil {
+		return "", fmt.Errorf("parse public key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,291 @@
+package sshkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/common"
+	"goterm/backend/internal/security/keyring"
+)
+
+const (
+	TypeED25519 = "ed25519"
+	TypeECDSA   = "ecdsa"
+	TypeRSA     = "rsa"
+
+	defaultRSABits   = 3072
+	minRSABits       = 2048
+	defaultECDSABits = 256
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Key is a key pair stored by the manager. Path is the private key; the
+// public half lives next to it with a .pub suffix.
+type Key struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment"`
+	Path        string `json:"path"`
+	PublicKey   string `json:"publicKey"`
+	Encrypted   bool   `json:"encrypted"`
+	CreatedAt   int64  `json:"createdAt"`
+}
+
+type GenerateOptions struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Bits       int    `json:"bits"`
+	Comment    string `json:"comment"`
+	Passphrase string `json:"passphrase"`
+	// ProfileID, when set, receives the passphrase in the keyring so the
+	// profile can unlock the key on connect.
+	ProfileID string `json:"profileId"`
+}
+
+// Manager keeps OpenSSH key pairs in a directory.
+type Manager struct {
+	dir string
+}
+
+func NewManager(dir string) *Manager {
+	return &Manager{dir: dir}
+}
+
+func (m *Manager) Generate(opts GenerateOptions) (Key, error) {
+	if err := m.checkNew(opts.Name); err != nil {
+		return Key{}, err
+	}
+
+	private, err := generate(opts.Type, opts.Bits)
+	if err != nil {
+		return Key{}, err
+	}
+	if err := m.write(opts.Name, private, opts.Comment, opts.Passphrase); err != nil {
+		return Key{}, err
+	}
+
+	if opts.ProfileID != "" && opts.Passphrase != "" {
+		if err := keyring.SetPrivateKeyPassphrase(opts.ProfileID, opts.Passphrase); err != nil {
+			return Key{}, fmt.Errorf("store passphrase: %w", err)
+		}
+	}
+	return m.Get(opts.Name)
+}
+
+// Convert reads a private key in PEM (PKCS#1, SEC1) or PKCS#8 form and stores
+// it under name in OpenSSH format, encrypted with passphrase when one is set.
+func (m *Manager) Convert(srcPath, passphrase, name, comment string) (Key, error) {
+	if err := m.checkNew(name); err != nil {
+		return Key{}, err
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return Key{}, err
+	}
+	var private any
+	if passphrase != "" {
+		private, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	} else {
+		private, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return Key{}, errors.New("key is encrypted; passphrase required")
+		}
+		return Key{}, fmt.Errorf("parse private key: %w", err)
+	}
+
+	if err := m.write(name, private, comment, passphrase); err != nil {
+		return Key{}, err
+	}
+	return m.Get(name)
+}
+
+func (m *Manager) List() ([]Key, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Key{}, nil
+		}
+		return nil, err
+	}
+
+	items := []Key{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".pub")
+		if !ok || entry.IsDir() || !validName.MatchString(name) {
+			continue
+		}
+		key, err := m.Get(name)
+		if err != nil {
+			continue
+		}
+		items = append(items, key)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func (m *Manager) Get(name string) (Key, error) {
+	if !validName.MatchString(name) {
+		return Key{}, common.ErrNotFound
+	}
+	path := m.path(name)
+
+	pubData, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Key{}, common.ErrNotFound
+		}
+		return Key{}, err
+	}
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(pubData)
+	if err != nil {
+		return Key{}, fmt.Errorf("parse public key: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Key{}, common.ErrNotFound
+		}
+		return Key{}, err
+	}
+
+	key := Key{
+		Name:        name,
+		Type:        pub.Type(),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		Comment:     comment,
+		Path:        path,
+		PublicKey:   strings.TrimSpace(string(pubData)),
+		CreatedAt:   info.ModTime().Unix(),
+	}
+	if privData, err := os.ReadFile(path); err == nil {
+		_, err := ssh.ParseRawPrivateKey(privData)
+		var missing *ssh.PassphraseMissingError
+		key.Encrypted = errors.As(err, &missing)
+	}
+	return key, nil
+}
+
+func (m *Manager) Delete(name string) error {
+	if _, err := m.Get(name); err != nil {
+		return err
+	}
+	path := m.path(name)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path + ".pub"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ExportPublicKey writes the public key in authorized_keys format to
+// destPath.
+func (m *Manager) ExportPublicKey(name, destPath string) error {
+	key, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(destPath, []byte(key.PublicKey+"\n"), 0o644)
+}
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name)
+}
+
+func (m *Manager) checkNew(name string) error {
+	if !validName.MatchString(name) {
+		return errors.New("key name may only contain letters, digits, '.', '_' and '-'")
+	}
+	if _, err := os.Stat(m.path(name)); err == nil {
+		return fmt.Errorf("key %q already exists", name)
+	}
+	return nil
+}
+
+func (m *Manager) write(name string, private any, comment, passphrase string) error {
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return err
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, comment)
+	}
+	if err != nil {
+		return err
+	}
+
+	pub := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if comment != "" {
+		pub += " " + comment
+	}
+
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	path := m.path(name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".pub", []byte(pub+"\n"), 0o644); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func generate(keyType string, bits int) (any, error) {
+	switch keyType {
+	case "", TypeED25519:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case TypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, defaultECDSABits:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA key size: %d", bits)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case TypeRSA:
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		if bits < minRSABits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSABits)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/profiles"
+)
+
+type CertificateInfo struct {
+	KeyID       string   `json:"keyId"`
+	Serial      uint64   `json:"serial"`
+	Principals  []string `json:"principals"`
+	ValidAfter  int64    `json:"validAfter"`
+	ValidBefore int64    `json:"validBefore"`
+}
+
+// loadCertificate reads the OpenSSH user certificate paired with keyPath:
+// certPath when set, otherwise <keyPath>-cert.pub if it exists. It returns nil
+// when the key has no certificate.
+func loadCertificate(keyPath, certPath string) (*ssh.Certificate, error) {
+	if certPath == "" {
+		certPath = keyPath + "-cert.pub"
+		if _, err := os.Stat(certPath); err != nil {
+			return nil, nil
+		}
+	}
+
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("certificate file does not contain an OpenSSH certificate")
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, errors.New("certificate is not a user certificate")
+	}
+	return cert, nil
+}
+
+func checkCertificateValidity(cert *ssh.Certificate, now time.Time) error {
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return fmt.Errorf("certificate %q is not valid before %s", cert.KeyId, certTime(cert.ValidAfter))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return fmt.Errorf("certificate %q expired at %s", cert.KeyId, certTime(cert.ValidBefore))
+	}
+	return nil
+}
+
+// profileCertificate describes the certificate a profile authenticates with,
+// if any.
+func profileCertificate(profile profiles.Profile) *CertificateInfo {
+	if profile.PrivateKeyPath == "" || !strings.Contains(profile.AuthType, "privateKey") {
+		return nil
+	}
+	cert, err := loadCertificate(profile.PrivateKeyPath, profile.CertificatePath)
+	if err != nil || cert == nil {
+		return nil
+	}
+
+	info := &CertificateInfo{
+		KeyID:      cert.KeyId,
+		Serial:     cert.Serial,
+		Principals: cert.ValidPrincipals,
+		ValidAfter: int64(cert.ValidAfter),
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		info.ValidBefore = int64(cert.ValidBefore)
+	}
+	return info
+}
+
+func certTime(value uint64) string {
+	return time.Unix(int64(value), 0).Format(time.RFC3339)
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/profiles"
+)
+
+// ChallengeFunc answers one keyboard-interactive round. It returns one answer
+// per question.
+type ChallengeFunc func(profileID, host, user, name, instruction string, questions []string, echos []bool) ([]string, error)
+
+// keyboardInteractive forwards server challenges to the challenge handler.
+// When the chain also loaded a password, a lone password prompt is answered
+// with it once so PAM setups asking for password and OTP in turn only prompt
+// for the code.
+func (m *Manager) keyboardInteractive(profile profiles.Profile, password *string) ssh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return nil, nil
+		}
+
+		if *password != "" && !passwordUsed && len(questions) == 1 && !echos[0] && isPasswordPrompt(questions[0]) {
+			passwordUsed = true
+			return []string{*password}, nil
+		}
+
+		m.mu.Lock()
+		challenge := m.challenge
+		m.mu.Unlock()
+		if challenge == nil {
+			return nil, errors.New("keyboard-interactive auth is not available")
+		}
+
+		answers, err := challenge(profile.ID, profileAddr(profile), profile.Username, name, instruction, questions, echos)
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) != len(questions) {
+			return nil, errors.New("keyboard-interactive answer count mismatch")
+		}
+		return answers, nil
+	}
+}
+
+func isPasswordPrompt(question string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(question)), "password")
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,40 +15,58 @@ import (
 	"goterm/backend/internal/profiles"
 	"goterm/backend/internal/security/hostkey"
 	"goterm/backend/internal/security/keyring"
+	"goterm/backend/internal/security/sshagent"
 )
 
 type Session struct {
-	ID        string
-	ProfileID string
-	Client    *ssh.Client
-	State     string
-	LastError string
-	stopCh    chan struct{}
+	ID           string
+	ProfileID    string
+	Client       *ssh.Client
+	State        string
+	LastError    string
+	FailedHop    *Hop
+	Certificate  *CertificateInfo
+	Attempt      int
+	MaxAttempts  int
+	route        *route
+	forwardAgent bool
+	stopCh       chan struct{}
 }
 
 type Status struct {
-	State     string `json:"state"`
-	LastError string `json:"lastError"`
+	State       string           `json:"state"`
+	LastError   string           `json:"lastError"`
+	FailedHop   *Hop             `json:"failedHop,omitempty"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+	Attempt     int              `json:"attempt,omitempty"`
+	MaxAttempts int              `json:"maxAttempts,omitempty"`
 }
 
 type StateEvent struct {
-	SessionID string `json:"sessionId"`
-	ProfileID string `json:"profileId"`
-	State     string `json:"state"`
-	Error     string `json:"error"`
+	SessionID   string `json:"sessionId"`
+	ProfileID   string `json:"profileId"`
+	State       string `json:"state"`
+	Error       string `json:"error"`
+	FailedHop   *Hop   `json:"failedHop,omitempty"`
+	Attempt     int    `json:"attempt,omitempty"`
+	MaxAttempts int    `json:"maxAttempts,omitempty"`
 }
 
 type Manager struct {
 	store    profiles.Store
 	hostKeys *hostkey.Verifier
+	agent    *sshagent.Agent
 	emitter  common.Emitter
 
+	challenge ChallengeFunc
+
 	mu        sync.Mutex
 	sessions  map[string]*Session
 	byProfile map[string]*Session
+	listeners []func(StateEvent)
 }
 
-func NewManager(store profiles.Store, hostKeys *hostkey.Verifier, emitter common.Emitter) *Manager {
+func NewManager(store profiles.Store, hostKeys *hostkey.Verifier, agent *sshagent.Agent, emitter common.Emitter) *Manager {
 	if emitter == nil {
 		emitter = common.NopEmitter{}
 	}
@@ -57,6 +74,7 @@ func NewManager(store profiles.Store, hostKeys *hostkey.Verifier, emitter common
 	return &Manager{
 		store:     store,
 		hostKeys:  hostKeys,
+		agent:     agent,
 		emitter:   emitter,
 		sessions:  map[string]*Session{},
 		byProfile: map[string]*Session{},
@@ -65,10 +83,17 @@ func NewManager(store profiles.Store, hostKeys *hostkey.Verifier, emitter common
 
 func (m *Manager) Connect(ctx context.Context, profileID string) (string, error) {
 	m.mu.Lock()
-	if existing, ok := m.byProfile[profileID]; ok && existing.State == "connected" {
-		id := existing.ID
-		m.mu.Unlock()
-		return id, nil
+	if existing, ok := m.byProfile[profileID]; ok {
+		switch existing.State {
+		case "connected":
+			id := existing.ID
+			m.mu.Unlock()
+			return id, nil
+		case "reconnecting":
+			// A second session would race the reconnect for byProfile.
+			m.mu.Unlock()
+			return "", errors.New("session is reconnecting; disconnect it first")
+		}
 	}
 	m.mu.Unlock()
 
@@ -77,34 +102,34 @@ func (m *Manager) Connect(ctx context.Context, profileID string) (string, error)
 		return "", err
 	}
 
-	config, err := m.clientConfig(profile)
-	if err != nil {
-		return "", err
-	}
-
-	port := profile.Port
-	if port <= 0 {
-		port = 22
-	}
-	addr := net.JoinHostPort(profile.Host, strconv.Itoa(port))
-
-	client, err := ssh.Dial("tcp", addr, config)
+	r, err := m.dial(ctx, profile)
 	if err != nil {
+		failed := &Session{
+			ProfileID: profile.ID,
+			State:     "disconnected",
+			LastError: err.Error(),
+			FailedHop: failedHopOf(err),
+		}
+		m.emitState(failed, err.Error())
 		return "", err
 	}
 
 	id, err := common.NewID()
 	if err != nil {
-		_ = client.Close()
+		_ = r.Close()
 		return "", err
 	}
 
 	sess := &Session{
-		ID:        id,
-		ProfileID: profile.ID,
-		Client:    client,
-		State:     "connected",
-		stopCh:    make(chan struct{}),
+		ID:           id,
+		ProfileID:    profile.ID,
+		Client:       r.client,
+		State:        "connected",
+		Certificate:  profileCertificate(profile),
+		MaxAttempts:  reconnectAttempts(profile),
+		route:        r,
+		forwardAgent: profile.ForwardAgent,
+		stopCh:       make(chan struct{}),
 	}
 
 	m.mu.Lock()
@@ -112,6 +137,9 @@ func (m *Manager) Connect(ctx context.Context, profileID string) (string, error)
 	m.byProfile[profile.ID] = sess
 	m.mu.Unlock()
 
+	// Usage stats are best effort; a failed update must not fail the connect.
+	_ = m.store.RecordConnect(ctx, profile.ID, time.Now())
+
 	m.emitState(sess, "")
 
 	go m.keepAlive(sess)
@@ -127,22 +155,52 @@ func (m *Manager) Disconnect(sessionID string) error {
 
 	m.mu.Lock()
 	delete(m.sessions, sessionID)
-	delete(m.byProfile, sess.ProfileID)
+	if m.byProfile[sess.ProfileID] == sess {
+		delete(m.byProfile, sess.ProfileID)
+	}
+	r := sess.route
+	sess.State = "disconnected"
+	sess.Attempt = 0
 	m.mu.Unlock()
 
 	close(sess.stopCh)
-	_ = sess.Client.Close()
-	sess.State = "disconnected"
+	_ = r.Close()
 	m.emitState(sess, "")
 	return nil
 }
 
+// SetChallengeFunc installs the handler that answers keyboard-interactive
+// challenges, normally by asking the user.
+func (m *Manager) SetChallengeFunc(fn ChallengeFunc) {
+	m.mu.Lock()
+	m.challenge = fn
+	m.mu.Unlock()
+}
+
+// AddStateListener registers fn to be called with every session:state event,
+// after it has been emitted to the UI.
+func (m *Manager) AddStateListener(fn func(StateEvent)) {
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	m.mu.Unlock()
+}
+
 func (m *Manager) Status(sessionID string) (Status, error) {
 	sess, err := m.getSession(sessionID)
 	if err != nil {
 		return Status{}, err
 	}
-	return Status{State: sess.State, LastError: sess.LastError}, nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Status{
+		State:       sess.State,
+		LastError:   sess.LastError,
+		FailedHop:   sess.FailedHop,
+		Certificate: sess.Certificate,
+		Attempt:     sess.Attempt,
+		MaxAttempts: sess.MaxAttempts,
+	}, nil
 }
 
 func (m *Manager) GetClient(sessionID string) (*ssh.Client, error) {
@@ -150,12 +208,64 @@ func (m *Manager) GetClient(sessionID string) (*ssh.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if sess.State != "connected" {
 		return nil, errors.New("session not connected")
 	}
 	return sess.Client, nil
 }
 
+// SessionFor returns the connected session of a profile, if there is one.
+func (m *Manager) SessionFor(profileID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sess, ok := m.byProfile[profileID]; ok && sess.State == "connected" {
+		return sess.ID, true
+	}
+	return "", false
+}
+
+// ProfileID returns the profile a session was opened for.
+func (m *Manager) ProfileID(sessionID string) (string, error) {
+	sess, err := m.getSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return sess.ProfileID, nil
+}
+
+// VerifyKey opens a throwaway connection to the profile's host that
+// authenticates with the private key at keyPath only, then closes it. Jump
+// hosts are reached the usual way.
+func (m *Manager) VerifyKey(ctx context.Context, profileID, keyPath string) error {
+	profile, err := m.store.Get(ctx, profileID)
+	if err != nil {
+		return err
+	}
+	profile.AuthType = "privateKey"
+	profile.PrivateKeyPath = keyPath
+	profile.CertificatePath = ""
+	profile.ForwardAgent = false
+
+	r, err := m.dial(ctx, profile)
+	if err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+// AgentForwarding reports whether terminals of the session should request
+// agent forwarding.
+func (m *Manager) AgentForwarding(sessionID string) bool {
+	sess, err := m.getSession(sessionID)
+	if err != nil {
+		return false
+	}
+	return sess.forwardAgent
+}
+
 func (m *Manager) getSession(sessionID string) (*Session, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -173,11 +283,32 @@ func (m *Manager) keepAlive(sess *Session) {
 	for {
 		select {
 		case <-ticker.C:
-			if _, _, err := sess.Client.SendRequest("keepalive@goterm", true, nil); err != nil {
-				sess.LastError = err.Error()
-				sess.State = "disconnected"
-				m.emitState(sess, err.Error())
-				return
+			m.mu.Lock()
+			r := sess.route
+			m.mu.Unlock()
+
+			if _, _, err := r.client.SendRequest("keepalive@goterm", true, nil); err != nil {
+				errMsg := err.Error()
+				if stderr := r.proxyStderr(); stderr != "" {
+					errMsg += ": " + stderr
+				}
+				failedHop := r.failedHop()
+				_ = r.Close()
+
+				// Disconnect closes the client, which fails a keepalive in
+				// flight; that is not a dropped connection.
+				m.mu.Lock()
+				if !m.isActive(sess) {
+					m.mu.Unlock()
+					return
+				}
+				sess.LastError = errMsg
+				sess.FailedHop = failedHop
+				m.mu.Unlock()
+
+				if !m.reconnect(sess) {
+					return
+				}
 			}
 		case <-sess.stopCh:
 			return
@@ -185,42 +316,79 @@ func (m *Manager) keepAlive(sess *Session) {
 	}
 }
 
+// isActive reports whether sess has not been disconnected. m.mu must be held.
+func (m *Manager) isActive(sess *Session) bool {
+	return m.sessions[sess.ID] == sess
+}
+
 func (m *Manager) emitState(sess *Session, errMsg string) {
-	m.emitter.Emit("session:state", StateEvent{
-		SessionID: sess.ID,
-		ProfileID: sess.ProfileID,
-		State:     sess.State,
-		Error:     errMsg,
-	})
+	m.mu.Lock()
+	event := StateEvent{
+		SessionID:   sess.ID,
+		ProfileID:   sess.ProfileID,
+		State:       sess.State,
+		Error:       errMsg,
+		FailedHop:   sess.FailedHop,
+		Attempt:     sess.Attempt,
+		MaxAttempts: sess.MaxAttempts,
+	}
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	m.emitter.Emit("session:state", event)
+	for _, fn := range listeners {
+		fn(event)
+	}
 }
 
+// clientConfig builds the SSH config for profile. AuthType may chain several
+// methods with "+", e.g. "password+keyboardInteractive", for servers that
+// require more than one to succeed.
 func (m *Manager) clientConfig(profile profiles.Profile) (*ssh.ClientConfig, error) {
 	var auths []ssh.AuthMethod
+	var password string
 
-	switch profile.AuthType {
-	case "password":
-		if !profile.UseKeyring {
-			return nil, errors.New("password auth requires keyring storage")
-		}
-		password, err := keyring.GetPassword(profile.ID)
-		if err != nil {
-			if errors.Is(err, keyring.ErrNotFound) {
-				return nil, errors.New("password not found in keyring")
+	for _, authType := range strings.Split(profile.AuthType, "+") {
+		switch authType {
+		case "password":
+			if !profile.UseKeyring {
+				return nil, errors.New("password auth requires keyring storage")
 			}
-			return nil, fmt.Errorf("load password: %w", err)
-		}
-		auths = append(auths, ssh.Password(password))
-	case "privateKey":
-		if profile.PrivateKeyPath == "" {
-			return nil, errors.New("private key path is required")
-		}
-		signer, err := loadSigner(profile.ID, profile.PrivateKeyPath)
-		if err != nil {
-			return nil, err
+			var err error
+			password, err = keyring.GetPassword(profile.ID)
+			if err != nil {
+				if errors.Is(err, keyring.ErrNotFound) {
+					return nil, errors.New("password not found in keyring")
+				}
+				return nil, fmt.Errorf("load password: %w", err)
+			}
+			auths = append(auths, ssh.Password(password))
+		case "privateKey":
+			if profile.PrivateKeyPath == "" {
+				return nil, errors.New("private key path is required")
+			}
+			signer, err := loadSigner(profile.ID, profile.PrivateKeyPath, profile.CertificatePath)
+			if err != nil {
+				return nil, err
+			}
+			auths = append(auths, ssh.PublicKeys(signer))
+		case "agent":
+			if m.agent == nil {
+				return nil, errors.New("ssh agent unavailable")
+			}
+			// Without a system agent, add the profile key to the built-in one
+			// so it is offered and forwarded alongside keys loaded before.
+			if !m.agent.HasSystem() && profile.PrivateKeyPath != "" {
+				if err := m.agent.LoadKey(profile.ID, profile.PrivateKeyPath, profile.Name); err != nil {
+					return nil, fmt.Errorf("load key into agent: %w", err)
+				}
+			}
+			auths = append(auths, ssh.PublicKeysCallback(m.agent.Signers))
+		case "keyboardInteractive":
+			auths = append(auths, ssh.KeyboardInteractive(m.keyboardInteractive(profile, &password)))
+		default:
+			return nil, fmt.Errorf("unsupported auth type: %s", authType)
 		}
-		auths = append(auths, ssh.PublicKeys(signer))
-	default:
-		return nil, fmt.Errorf("unsupported auth type: %s", profile.AuthType)
 	}
 
 	verifier := *m.hostKeys
@@ -245,7 +413,28 @@ func policyFromProfile(policy string) hostkey.Policy {
 	}
 }
 
-func loadSigner(profileID, keyPath string) (ssh.Signer, error) {
+// loadSigner parses the private key at keyPath and, when the key has an
+// OpenSSH certificate, returns a signer that presents it.
+func loadSigner(profileID, keyPath, certPath string) (ssh.Signer, error) {
+	signer, err := loadKey(profileID, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := loadCertificate(keyPath, certPath)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return signer, nil
+	}
+	if err := checkCertificateValidity(cert, time.Now()); err != nil {
+		return nil, err
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+func loadKey(profileID, keyPath string) (ssh.Signer, error) {
 	keyBytes, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/profiles"
+)
+
+const proxyStderrLimit = 4096
+
+// dialProxyCommand runs the profile's ProxyCommand locally and speaks SSH over
+// its stdin/stdout.
+func dialProxyCommand(profile profiles.Profile, addr string, config *ssh.ClientConfig) (*ssh.Client, *proxyConn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	command := expandProxyCommand(profile.ProxyCommand, host, port, profile.Username)
+
+	conn, err := startProxyCommand(command, addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	// The pipe has no deadlines, so a timer bounds the version and key
+	// exchange. It is stopped once the host key arrives: the host key and
+	// authentication prompts that follow wait for the user.
+	if config.Timeout > 0 && config.HostKeyCallback != nil {
+		timer := time.AfterFunc(config.Timeout, func() { _ = conn.Close() })
+		defer timer.Stop()
+		bounded := *config
+		hostKeyCallback := config.HostKeyCallback
+		bounded.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			timer.Stop()
+			return hostKeyCallback(hostname, remote, key)
+		}
+		config = &bounded
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		if stderr := conn.Stderr(); stderr != "" {
+			return nil, nil, fmt.Errorf("%w: %s", err, stderr)
+		}
+		return nil, nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), conn, nil
+}
+
+// expandProxyCommand substitutes the %h, %p, %r and %% tokens understood by
+// OpenSSH. Unknown tokens are left untouched.
+func expandProxyCommand(command, host, port, user string) string {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' || i+1 >= len(command) {
+			b.WriteByte(command[i])
+			continue
+		}
+		switch command[i+1] {
+		case 'h':
+			b.WriteString(host)
+		case 'p':
+			b.WriteString(port)
+		case 'r':
+			b.WriteString(user)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(command[i+1])
+		}
+		i++
+	}
+	return b.String()
+}
+
+type proxyConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *tailBuffer
+	addr   proxyAddr
+
+	closeOnce sync.Once
+}
+
+func startProxyCommand(command, addr string) (*proxyConn, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tailBuffer{limit: proxyStderrLimit}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &proxyConn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		addr:   proxyAddr(addr),
+	}, nil
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	return c.stdout.Read(p)
+}
+
+func (c *proxyConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *proxyConn) Close() error {
+	c.closeOnce.Do(func() {
+		_ = c.stdin.Close()
+		if c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+		_ = c.cmd.Wait()
+	})
+	return nil
+}
+
+// Stderr returns the tail of what the proxy command wrote to stderr.
+func (c *proxyConn) Stderr() string {
+	return strings.TrimSpace(c.stderr.String())
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	return proxyAddr("proxy-command")
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *proxyConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *proxyConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *proxyConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+type proxyAddr string
+
+func (a proxyAddr) Network() string {
+	return "proxy-command"
+}
+
+func (a proxyAddr) String() string {
+	return string(a)
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	limit int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"goterm/backend/internal/profiles"
+)
+
+const (
+	defaultReconnectAttempts = 5
+	reconnectBaseDelay       = time.Second
+	reconnectMaxDelay        = 30 * time.Second
+)
+
+// reconnectAttempts returns how many times a dropped session of profile is
+// re-dialed. Zero selects the default and a negative value disables reconnects.
+func reconnectAttempts(profile profiles.Profile) int {
+	switch {
+	case profile.ReconnectMaxAttempts < 0:
+		return 0
+	case profile.ReconnectMaxAttempts == 0:
+		return defaultReconnectAttempts
+	default:
+		return profile.ReconnectMaxAttempts
+	}
+}
+
+// reconnect re-dials the profile of a dropped session with exponential backoff
+// and swaps the new client into sess, so later GetClient calls pick it up. It
+// reports whether the session is connected again.
+func (m *Manager) reconnect(sess *Session) bool {
+	maxAttempts := sess.MaxAttempts
+	if profile, err := m.store.Get(context.Background(), sess.ProfileID); err == nil {
+		maxAttempts = reconnectAttempts(profile)
+	}
+
+	m.mu.Lock()
+	sess.MaxAttempts = maxAttempts
+	m.mu.Unlock()
+
+	delay := reconnectBaseDelay
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		m.mu.Lock()
+		if !m.isActive(sess) {
+			m.mu.Unlock()
+			return false
+		}
+		sess.State = "reconnecting"
+		sess.Attempt = attempt
+		lastError := sess.LastError
+		m.mu.Unlock()
+		m.emitState(sess, lastError)
+
+		select {
+		case <-time.After(delay):
+		case <-sess.stopCh:
+			return false
+		}
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+
+		profile, err := m.store.Get(context.Background(), sess.ProfileID)
+		if err != nil {
+			m.recordFailure(sess, err)
+			continue
+		}
+
+		r, err := m.dial(context.Background(), profile)
+		if err != nil {
+			m.recordFailure(sess, err)
+			continue
+		}
+
+		m.mu.Lock()
+		if !m.isActive(sess) {
+			m.mu.Unlock()
+			_ = r.Close()
+			return false
+		}
+		sess.route = r
+		sess.Client = r.client
+		sess.State = "connected"
+		sess.LastError = ""
+		sess.FailedHop = nil
+		sess.Certificate = profileCertificate(profile)
+		sess.Attempt = 0
+		m.mu.Unlock()
+
+		m.emitState(sess, "")
+		return true
+	}
+
+	m.mu.Lock()
+	if !m.isActive(sess) {
+		m.mu.Unlock()
+		return false
+	}
+	sess.State = "disconnected"
+	lastError := sess.LastError
+	m.mu.Unlock()
+	m.emitState(sess, lastError)
+	return false
+}
+
+func (m *Manager) recordFailure(sess *Session, err error) {
+	m.mu.Lock()
+	sess.LastError = err.Error()
+	sess.FailedHop = failedHopOf(err)
+	m.mu.Unlock()
+}
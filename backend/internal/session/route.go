@@ -0,0 +1,233 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+
+	"goterm/backend/internal/profiles"
+)
+
+// Hop identifies one step of a connection chain. Index counts from zero with
+// the target host last.
+type Hop struct {
+	Index     int    `json:"index"`
+	ProfileID string `json:"profileId"`
+	Name      string `json:"name"`
+	Addr      string `json:"addr"`
+}
+
+type HopError struct {
+	Hop Hop
+	Err error
+}
+
+func (e *HopError) Error() string {
+	name := e.Hop.Name
+	if name == "" {
+		name = e.Hop.Addr
+	}
+	return fmt.Sprintf("hop %d (%s): %v", e.Hop.Index+1, name, e.Err)
+}
+
+func (e *HopError) Unwrap() error {
+	return e.Err
+}
+
+func failedHopOf(err error) *Hop {
+	var hopErr *HopError
+	if !errors.As(err, &hopErr) {
+		return nil
+	}
+	hop := hopErr.Hop
+	return &hop
+}
+
+type hopClient struct {
+	hop    Hop
+	client *ssh.Client
+	owned  bool
+}
+
+// route is an established connection to a profile together with the bastions
+// it was tunnelled through. Bastions borrowed from other live sessions are not
+// owned and are left open on Close.
+type route struct {
+	target Hop
+	client *ssh.Client
+	hops   []hopClient
+	proxy  *proxyConn
+}
+
+func (r *route) Close() error {
+	err := r.client.Close()
+	r.closeOwned()
+	return err
+}
+
+// failedHop reports the first hop of the route that no longer answers.
+func (r *route) failedHop() *Hop {
+	if len(r.hops) == 0 {
+		return nil
+	}
+	for _, h := range r.hops {
+		if _, _, err := h.client.SendRequest("keepalive@goterm", true, nil); err != nil {
+			hop := h.hop
+			return &hop
+		}
+	}
+	target := r.target
+	return &target
+}
+
+func (m *Manager) dial(ctx context.Context, profile profiles.Profile) (*route, error) {
+	chain, err := m.resolveChain(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &route{}
+	var via *ssh.Client
+	start := 0
+
+	// Start from the furthest bastion that already has a live session.
+	for i := len(chain) - 2; i >= 0; i-- {
+		if client := m.connectedClient(chain[i].ID); client != nil {
+			r.hops = append(r.hops, hopClient{hop: hopFor(i, chain[i]), client: client})
+			via = client
+			start = i + 1
+			break
+		}
+	}
+
+	for i := start; i < len(chain); i++ {
+		hop := hopFor(i, chain[i])
+		client, err := m.dialHop(r, via, chain[i], hop.Addr)
+		if err != nil {
+			r.closeOwned()
+			if len(chain) > 1 {
+				return nil, &HopError{Hop: hop, Err: err}
+			}
+			return nil, err
+		}
+		if i == len(chain)-1 {
+			r.target = hop
+			r.client = client
+			break
+		}
+		r.hops = append(r.hops, hopClient{hop: hop, client: client, owned: true})
+		via = client
+	}
+
+	if profile.ForwardAgent {
+		if m.agent == nil {
+			_ = r.Close()
+			return nil, errors.New("ssh agent unavailable")
+		}
+		if err := m.agent.Forward(r.client); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("agent forwarding: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *route) closeOwned() {
+	for i := len(r.hops) - 1; i >= 0; i-- {
+		if r.hops[i].owned {
+			_ = r.hops[i].client.Close()
+		}
+	}
+}
+
+// proxyStderr returns what the route's proxy command, if any, wrote to stderr.
+func (r *route) proxyStderr() string {
+	if r.proxy == nil {
+		return ""
+	}
+	return r.proxy.Stderr()
+}
+
+// dialHop connects to one hop, either through the previous hop or from the
+// local machine. A profile's ProxyCommand is only used in the latter case.
+func (m *Manager) dialHop(r *route, via *ssh.Client, profile profiles.Profile, addr string) (*ssh.Client, error) {
+	config, err := m.clientConfig(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if via == nil {
+		if profile.ProxyCommand != "" {
+			client, proxy, err := dialProxyCommand(profile, addr, config)
+			if err != nil {
+				return nil, err
+			}
+			r.proxy = proxy
+			return client, nil
+		}
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// resolveChain returns the jump profiles of profile in dial order followed by
+// the profile itself.
+func (m *Manager) resolveChain(ctx context.Context, profile profiles.Profile) ([]profiles.Profile, error) {
+	seen := map[string]bool{profile.ID: true}
+	chain := make([]profiles.Profile, 0, len(profile.JumpProfileIDs)+1)
+	for _, jumpID := range profile.JumpProfileIDs {
+		if seen[jumpID] {
+			return nil, errors.New("jump host chain contains a loop")
+		}
+		seen[jumpID] = true
+
+		jump, err := m.store.Get(ctx, jumpID)
+		if err != nil {
+			return nil, fmt.Errorf("load jump profile %s: %w", jumpID, err)
+		}
+		chain = append(chain, jump)
+	}
+	return append(chain, profile), nil
+}
+
+func (m *Manager) connectedClient(profileID string) *ssh.Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.byProfile[profileID]
+	if !ok || sess.State != "connected" {
+		return nil
+	}
+	return sess.Client
+}
+
+func hopFor(index int, profile profiles.Profile) Hop {
+	return Hop{
+		Index:     index,
+		ProfileID: profile.ID,
+		Name:      profile.Name,
+		Addr:      profileAddr(profile),
+	}
+}
+
+func profileAddr(profile profiles.Profile) string {
+	port := profile.Port
+	if port <= 0 {
+		port = 22
+	}
+	return net.JoinHostPort(profile.Host, strconv.Itoa(port))
+}
@@ -0,0 +1,103 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	sftplib "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// AppendAuthorizedKey adds publicKey, in authorized_keys format, to
+// ~/.ssh/authorized_keys on the session's host. ~/.ssh is created with mode
+// 0700 and the file is kept at 0600. It reports false when the key was
+// already authorized.
+func (s *Service) AppendAuthorizedKey(sessionID, publicKey string) (bool, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return false, err
+	}
+	line := strings.TrimSpace(publicKey)
+
+	added := false
+	err = s.withClient(sessionID, func(client *sftplib.Client) error {
+		home, err := client.Getwd()
+		if err != nil {
+			return err
+		}
+		sshDir := path.Join(home, ".ssh")
+		authPath := path.Join(sshDir, "authorized_keys")
+
+		if info, err := client.Stat(sshDir); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := client.Mkdir(sshDir); err != nil {
+				return err
+			}
+		} else if !info.IsDir() {
+			return errors.New("~/.ssh exists and is not a directory")
+		}
+		if err := client.Chmod(sshDir, 0o700); err != nil {
+			return err
+		}
+
+		existing, err := readRemote(client, authPath)
+		if err != nil {
+			return err
+		}
+		if hasAuthorizedKey(existing, key) {
+			return nil
+		}
+
+		file, err := client.OpenFile(authPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		data := line + "\n"
+		if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+			data = "\n" + data
+		}
+		if _, err := file.Write([]byte(data)); err != nil {
+			return err
+		}
+		added = true
+		return client.Chmod(authPath, 0o600)
+	})
+	return added, err
+}
+
+func readRemote(client *sftplib.Client, remotePath string) ([]byte, error) {
+	file, err := client.Open(remotePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// hasAuthorizedKey compares keys rather than lines so options and comments
+// do not hide a duplicate.
+func hasAuthorizedKey(data []byte, key ssh.PublicKey) bool {
+	want := key.Marshal()
+	for len(data) > 0 {
+		pub, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return false
+		}
+		if bytes.Equal(pub.Marshal(), want) {
+			return true
+		}
+		data = rest
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package snippets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LibraryVersion = 1
+
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+// Library is the file form of a set of snippets.
+type Library struct {
+	Version    int       `json:"version"`
+	ExportedAt int64     `json:"exportedAt"`
+	Snippets   []Snippet `json:"snippets"`
+}
+
+// ImportOptions decides what happens to a snippet whose name already exists.
+type ImportOptions struct {
+	Conflict string `json:"conflict"`
+}
+
+type ImportItem struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Action string `json:"action"`
+}
+
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// Export encodes the snippets with the given IDs, or all when ids is empty.
+func Export(ctx context.Context, store Store, ids []string, format string) ([]byte, error) {
+	items, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		wanted := map[string]bool{}
+		for _, id := range ids {
+			wanted[id] = true
+		}
+		selected := []Snippet{}
+		for _, item := range items {
+			if wanted[item.ID] {
+				selected = append(selected, item)
+			}
+		}
+		items = selected
+	}
+	if items == nil {
+		items = []Snippet{}
+	}
+
+	data, err := json.MarshalIndent(Library{
+		Version:    LibraryVersion,
+		ExportedAt: time.Now().Unix(),
+		Snippets:   items,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "", FormatJSON:
+		return data, nil
+	case FormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unsupported library format: %s", format)
+	}
+}
+
+// Import adds the snippets of a JSON or YAML library to store. Names are
+// compared without regard to case.
+func Import(ctx context.Context, store Store, data []byte, opts ImportOptions) ([]ImportItem, error) {
+	library, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	conflict := opts.Conflict
+	if conflict == "" {
+		conflict = ConflictSkip
+	}
+	if conflict != ConflictSkip && conflict != ConflictOverwrite && conflict != ConflictRename {
+		return nil, fmt.Errorf("unknown conflict mode: %s", conflict)
+	}
+
+	existing, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]string{}
+	for _, item := range existing {
+		byName[strings.ToLower(item.Name)] = item.ID
+	}
+
+	report := []ImportItem{}
+	for _, snippet := range library.Snippets {
+		snippet.ID = ""
+		action := "create"
+		if id, ok := byName[strings.ToLower(strings.TrimSpace(snippet.Name))]; ok {
+			switch conflict {
+			case ConflictSkip:
+				report = append(report, ImportItem{Name: snippet.Name, ID: id, Action: "skip"})
+				continue
+			case ConflictOverwrite:
+				snippet.ID = id
+				action = "overwrite"
+			case ConflictRename:
+				snippet.Name = uniqueName(snippet.Name, byName)
+				action = "rename"
+			}
+		}
+
+		normalized, err := Normalize(snippet)
+		if err != nil {
+			return report, fmt.Errorf("import snippet %q: %w", snippet.Name, err)
+		}
+		id, err := store.Save(ctx, normalized)
+		if err != nil {
+			return report, fmt.Errorf("import snippet %q: %w", snippet.Name, err)
+		}
+		byName[strings.ToLower(normalized.Name)] = id
+		report = append(report, ImportItem{Name: normalized.Name, ID: id, Action: action})
+	}
+	return report, nil
+}
+
+func decode(data []byte) (*Library, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty snippet library")
+	}
+	if trimmed[0] != '{' {
+		var doc any
+		if err := yaml.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("parse snippet library: %w", err)
+		}
+		var err error
+		if trimmed, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("parse snippet library: %w", err)
+		}
+	}
+
+	var library Library
+	if err := json.Unmarshal(trimmed, &library); err != nil {
+		return nil, fmt.Errorf("parse snippet library: %w", err)
+	}
+	if library.Version < 1 || library.Version > LibraryVersion {
+		return nil, fmt.Errorf("unsupported snippet library version %d", library.Version)
+	}
+	return &library, nil
+}
+
+func uniqueName(name string, taken map[string]string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if _, ok := taken[strings.ToLower(candidate)]; !ok {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package snippets
+
+import (
+	"context"
+	"strings"
+
+	"goterm/backend/internal/batch"
+)
+
+// Terminals writes input to an interactive terminal.
+type Terminals interface {
+	Write(termID, data string) error
+}
+
+// Runner starts a non-interactive command on several hosts.
+type Runner interface {
+	Start(ctx context.Context, req batch.Request) (string, error)
+}
+
+// Service renders stored snippets and sends them to a terminal or runs them
+// through the exec service.
+type Service struct {
+	store     Store
+	terminals Terminals
+	runner    Runner
+}
+
+func NewService(store Store, terminals Terminals, runner Runner) *Service {
+	return &Service{store: store, terminals: terminals, runner: runner}
+}
+
+// Save validates the snippet and stores it.
+func (s *Service) Save(ctx context.Context, snippet Snippet) (string, error) {
+	snippet, err := Normalize(snippet)
+	if err != nil {
+		return "", err
+	}
+	return s.store.Save(ctx, snippet)
+}
+
+func (s *Service) Render(ctx context.Context, id string, values map[string]string) (string, error) {
+	snippet, err := s.store.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return Render(snippet, values)
+}
+
+// Send types the rendered snippet into a terminal. Line breaks become carriage
+// returns as if typed; execute also presses Enter after the last line.
+func (s *Service) Send(ctx context.Context, id, termID string, values map[string]string, execute bool) error {
+	body, err := s.Render(ctx, id, values)
+	if err != nil {
+		return err
+	}
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.TrimRight(body, "\n")
+	body = strings.ReplaceAll(body, "\n", "\r")
+	if execute {
+		body += "\r"
+	}
+	return s.terminals.Write(termID, body)
+}
+
+// Run executes the rendered snippet on the hosts of req and returns the exec
+// job ID. req.Command is replaced by the snippet.
+func (s *Service) Run(ctx context.Context, id string, values map[string]string, req batch.Request) (string, error) {
+	body, err := s.Render(ctx, id, values)
+	if err != nil {
+		return "", err
+	}
+	req.Command = body
+	return s.runner.Start(ctx, req)
+}
@@ -0,0 +1,10 @@
+package snippets
+
+import "context"
+
+type Store interface {
+	List(ctx context.Context) ([]Snippet, error)
+	Get(ctx context.Context, id string) (Snippet, error)
+	Save(ctx context.Context, snippet Snippet) (string, error)
+	Delete(ctx context.Context, id string) error
+}
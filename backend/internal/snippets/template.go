@@ -0,0 +1,148 @@
+package snippets
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	validParam  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Placeholders returns the distinct parameter names used in body, in order of
+// first use.
+func Placeholders(body string) []string {
+	names := []string{}
+	for _, match := range placeholder.FindAllStringSubmatch(body, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Normalize checks a snippet before it is stored. Placeholders without a
+// declared parameter get a string parameter; parameters with no type become
+// strings.
+func Normalize(snippet Snippet) (Snippet, error) {
+	snippet.Name = strings.TrimSpace(snippet.Name)
+	if snippet.Name == "" {
+		return Snippet{}, errors.New("snippet name is required")
+	}
+	if strings.TrimSpace(snippet.Body) == "" {
+		return Snippet{}, errors.New("snippet body is required")
+	}
+
+	params := []Param{}
+	declared := map[string]bool{}
+	for _, param := range snippet.Params {
+		if !validParam.MatchString(param.Name) {
+			return Snippet{}, fmt.Errorf("invalid parameter name %q", param.Name)
+		}
+		if declared[param.Name] {
+			return Snippet{}, fmt.Errorf("duplicate parameter %q", param.Name)
+		}
+		declared[param.Name] = true
+		if param.Type == "" {
+			param.Type = ParamString
+		}
+		switch param.Type {
+		case ParamString, ParamInt, ParamBool:
+		case ParamChoice:
+			if len(param.Choices) == 0 {
+				return Snippet{}, fmt.Errorf("parameter %q needs choices", param.Name)
+			}
+		default:
+			return Snippet{}, fmt.Errorf("parameter %q has unknown type %q", param.Name, param.Type)
+		}
+		if param.Default != "" {
+			if _, err := convert(param, param.Default); err != nil {
+				return Snippet{}, fmt.Errorf("default of %w", err)
+			}
+		}
+		params = append(params, param)
+	}
+	for _, name := range Placeholders(snippet.Body) {
+		if !declared[name] {
+			params = append(params, Param{Name: name, Type: ParamString, Required: true})
+		}
+	}
+	snippet.Params = params
+	if snippet.Tags == nil {
+		snippet.Tags = []string{}
+	}
+	return snippet, nil
+}
+
+// Render fills the placeholders of the snippet from values, falling back to
+// parameter defaults. String values are quoted unless the parameter is Raw.
+func Render(snippet Snippet, values map[string]string) (string, error) {
+	params := map[string]Param{}
+	for _, param := range snippet.Params {
+		params[param.Name] = param
+	}
+
+	resolved := map[string]string{}
+	for _, name := range Placeholders(snippet.Body) {
+		param, ok := params[name]
+		if !ok {
+			param = Param{Name: name, Type: ParamString, Required: true}
+		}
+		value, ok := values[name]
+		if !ok || value == "" {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return "", fmt.Errorf("parameter %q is required", name)
+		}
+		value, err := convert(param, value)
+		if err != nil {
+			return "", err
+		}
+		if param.Type == ParamString && !param.Raw {
+			value = shellQuote(value)
+		}
+		resolved[name] = value
+	}
+
+	return placeholder.ReplaceAllStringFunc(snippet.Body, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		return resolved[name]
+	}), nil
+}
+
+// convert validates value against the parameter type and returns it in
+// canonical form. Empty values pass; Render checks Required separately.
+func convert(param Param, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	switch param.Type {
+	case ParamInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("parameter %q must be an integer", param.Name)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case ParamBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("parameter %q must be true or false", param.Name)
+		}
+		return strconv.FormatBool(b), nil
+	case ParamChoice:
+		if !slices.Contains(param.Choices, value) {
+			return "", fmt.Errorf("parameter %q must be one of %s", param.Name, strings.Join(param.Choices, ", "))
+		}
+	}
+	return value, nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
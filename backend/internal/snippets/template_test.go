@@ -0,0 +1,42 @@
+package snippets
+
+import "testing"
+
+func TestRenderQuotesStringValues(t *testing.T) {
+	snippet := Snippet{
+		Body: "grep {{pattern}} {{file}} | head -n {{lines}}",
+		Params: []Param{
+			{Name: "pattern", Type: ParamString},
+			{Name: "file", Type: ParamString},
+			{Name: "lines", Type: ParamInt},
+		},
+	}
+
+	got, err := Render(snippet, map[string]string{
+		"pattern": "foo; rm -rf ~",
+		"file":    "it's $(whoami).log",
+		"lines":   "10",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `grep 'foo; rm -rf ~' 'it'\''s $(whoami).log' | head -n 10`
+	if got != want {
+		t.Fatalf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestRenderRawParam(t *testing.T) {
+	snippet := Snippet{
+		Body:   "ls {{flags}}",
+		Params: []Param{{Name: "flags", Type: ParamString, Raw: true}},
+	}
+
+	got, err := Render(snippet, map[string]string{"flags": "-la --color=auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ls -la --color=auto"; got != want {
+		t.Fatalf("Render = %q, want %q", got, want)
+	}
+}
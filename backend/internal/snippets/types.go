@@ -0,0 +1,34 @@
+package snippets
+
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamBool   = "bool"
+	ParamChoice = "choice"
+)
+
+// Snippet is a saved command. Body may contain {{name}} placeholders that are
+// filled from Params when the snippet is rendered.
+type Snippet struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Body        string   `json:"body"`
+	Params      []Param  `json:"params"`
+	CreatedAt   int64    `json:"createdAt"`
+	UpdatedAt   int64    `json:"updatedAt"`
+}
+
+// Param describes one placeholder. Choices applies to the choice type only.
+// String values are single-quoted for POSIX shells so they stay one argument;
+// Raw inserts them as typed, for values that are meant to be shell syntax.
+type Param struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Default     string   `json:"default"`
+	Required    bool     `json:"required"`
+	Choices     []string `json:"choices,omitempty"`
+	Raw         bool     `json:"raw"`
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// migration upgrades a database from version-1 to version. Migrations run in
+// order, each in its own transaction, and are recorded in schema_version.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+const schemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at INTEGER NOT NULL
+);
+`
+
+// migrate brings the database at path up to the latest migration. Databases
+// written before versioning existed report version 0; the baseline and column
+// migrations are idempotent so they apply cleanly on top of them. When there
+// is existing data it is copied next to the database before anything changes.
+func migrate(db *sql.DB, path string, migrations []migration) error {
+	if _, err := db.Exec(schemaVersionTable); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	hasData, err := hasUserTables(db)
+	if err != nil {
+		return err
+	}
+	if hasData {
+		if err := backup(db, fmt.Sprintf("%s.v%d.bak", path, current)); err != nil {
+			return fmt.Errorf("backup before migration: %w", err)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func apply(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.up(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		m.version, m.name, time.Now().Unix(),
+	); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func hasUserTables(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_version'",
+	).Scan(&count)
+	return count > 0, err
+}
+
+// backup writes a consistent copy of the database to dest, replacing an
+// older backup of the same version.
+func backup(db *sql.DB, dest string) error {
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("VACUUM INTO '%s'", strings.ReplaceAll(dest, "'", "''")))
+	return err
+}
+
+func execMigration(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+func addColumnsMigration(table string, columns []column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		return ensureColumns(tx, table, columns)
+	}
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openBaseline writes a profiles database as it looked before versioning
+// existed: the baseline table with one row and no schema_version.
+func openBaseline(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profiles.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+        INSERT INTO profiles (id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy)
+        VALUES ('p1', 'web', 'prod', 'web.example.com', 2222, 'deploy', 'key', '/keys/id_ed25519', 0, 'strict')
+    `); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func appliedVersions(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	rows, err := db.Query("SELECT version FROM schema_version ORDER BY version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+func TestMigrateUpgradesBaseline(t *testing.T) {
+	path := openBaseline(t)
+
+	store, err := OpenProfileStore(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	versions := appliedVersions(t, store.db)
+	if len(versions) != len(profileMigrations) {
+		t.Fatalf("applied versions = %v, want %d migrations", versions, len(profileMigrations))
+	}
+	for i, m := range profileMigrations {
+		if versions[i] != m.version {
+			t.Fatalf("applied versions = %v, want %d at %d", versions, m.version, i)
+		}
+	}
+
+	backupPath := path + ".v0.bak"
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	p, err := store.Get(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if p.Name != "web" || p.Host != "web.example.com" || p.Port != 2222 || p.Group != "prod" {
+		t.Fatalf("profile did not survive migration: %+v", p)
+	}
+	if p.ProxyCommand != "" || p.ForwardAgent || p.ReconnectMaxAttempts != 0 {
+		t.Fatalf("new columns have unexpected defaults: %+v", p)
+	}
+	if err := store.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening an up-to-date database changes nothing and takes no backup.
+	if err := os.Remove(backupPath); err != nil {
+		t.Fatal(err)
+	}
+	store, err = OpenProfileStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.db.Close()
+	if again := appliedVersions(t, store.db); len(again) != len(versions) {
+		t.Fatalf("reopen applied versions = %v, want %v", again, versions)
+	}
+	if _, err := os.Stat(backupPath); !os.IsNotExist(err) {
+		t.Fatalf("reopen wrote a backup: %v", err)
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	path := openBaseline(t)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	failing := errors.New("boom")
+	migrations := append(append([]migration{}, profileMigrations...), migration{
+		version: len(profileMigrations) + 1,
+		name:    "broken",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("CREATE TABLE half_done (id TEXT)"); err != nil {
+				return err
+			}
+			return failing
+		},
+	})
+
+	if err := migrate(db, path, migrations); !errors.Is(err, failing) {
+		t.Fatalf("migrate error = %v, want %v", err, failing)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("failed migration left its table behind")
+	}
+	versions := appliedVersions(t, db)
+	if len(versions) != len(profileMigrations) {
+		t.Fatalf("applied versions = %v, want only the %d working migrations", versions, len(profileMigrations))
+	}
+}
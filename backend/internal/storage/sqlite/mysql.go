@@ -27,6 +27,10 @@ CREATE TABLE IF NOT EXISTS mysql_profiles (
 );
 `
 
+var mysqlMigrations = []migration{
+	{version: 1, name: "baseline", up: execMigration(mysqlSchema)},
+}
+
 func OpenMySQLProfileStore(path string) (*MySQLProfileStore, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return nil, err
@@ -39,7 +43,7 @@ func OpenMySQLProfileStore(path string) (*MySQLProfileStore, error) {
 
 	db.SetMaxOpenConns(1)
 
-	if _, err := db.Exec(mysqlSchema); err != nil {
+	if err := migrate(db, path, mysqlMigrations); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
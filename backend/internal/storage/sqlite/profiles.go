@@ -3,6 +3,8 @@ package sqlite
 import (
     "context"
     "database/sql"
+    "strings"
+    "time"
 
     "goterm/backend/internal/common"
     "goterm/backend/internal/profiles"
@@ -14,7 +16,9 @@ type ProfileStore struct {
 
 func (s *ProfileStore) List(ctx context.Context) ([]profiles.Profile, error) {
     rows, err := s.db.QueryContext(ctx, `
-        SELECT id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy
+        SELECT id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy,
+               jump_profile_ids, proxy_command, reconnect_max_attempts, forward_agent, certificate_path,
+               favorite, last_connected_at, connect_count
         FROM profiles
         ORDER BY group_name, name
     `)
@@ -27,6 +31,9 @@ func (s *ProfileStore) List(ctx context.Context) ([]profiles.Profile, error) {
     for rows.Next() {
         var p profiles.Profile
         var useKeyringInt int
+        var jumpIDs string
+        var forwardAgentInt int
+        var favoriteInt int
         if err := rows.Scan(
             &p.ID,
             &p.Name,
@@ -38,27 +45,54 @@ func (s *ProfileStore) List(ctx context.Context) ([]profiles.Profile, error) {
             &p.PrivateKeyPath,
             &useKeyringInt,
             &p.KnownHostsPolicy,
+            &jumpIDs,
+            &p.ProxyCommand,
+            &p.ReconnectMaxAttempts,
+            &forwardAgentInt,
+            &p.CertificatePath,
+            &favoriteInt,
+            &p.LastConnectedAt,
+            &p.ConnectCount,
         ); err != nil {
             return nil, err
         }
         p.UseKeyring = useKeyringInt != 0
+        p.JumpProfileIDs = splitIDs(jumpIDs)
+        p.ForwardAgent = forwardAgentInt != 0
+        p.Favorite = favoriteInt != 0
         items = append(items, p)
     }
     if err := rows.Err(); err != nil {
         return nil, err
     }
+    if err := rows.Close(); err != nil {
+        return nil, err
+    }
+
+    tags, err := s.tags(ctx, "")
+    if err != nil {
+        return nil, err
+    }
+    for i := range items {
+        items[i].Tags = tags[items[i].ID]
+    }
     return items, nil
 }
 
 func (s *ProfileStore) Get(ctx context.Context, id string) (profiles.Profile, error) {
     row := s.db.QueryRowContext(ctx, `
-        SELECT id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy
+        SELECT id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy,
+               jump_profile_ids, proxy_command, reconnect_max_attempts, forward_agent, certificate_path,
+               favorite, last_connected_at, connect_count
         FROM profiles
         WHERE id = ?
     `, id)
 
     var p profiles.Profile
     var useKeyringInt int
+    var jumpIDs string
+    var forwardAgentInt int
+    var favoriteInt int
     if err := row.Scan(
         &p.ID,
         &p.Name,
@@ -70,6 +104,14 @@ func (s *ProfileStore) Get(ctx context.Context, id string) (profiles.Profile, er
         &p.PrivateKeyPath,
         &useKeyringInt,
         &p.KnownHostsPolicy,
+        &jumpIDs,
+        &p.ProxyCommand,
+        &p.ReconnectMaxAttempts,
+        &forwardAgentInt,
+        &p.CertificatePath,
+        &favoriteInt,
+        &p.LastConnectedAt,
+        &p.ConnectCount,
     ); err != nil {
         if err == sql.ErrNoRows {
             return profiles.Profile{}, common.ErrNotFound
@@ -77,6 +119,15 @@ func (s *ProfileStore) Get(ctx context.Context, id string) (profiles.Profile, er
         return profiles.Profile{}, err
     }
     p.UseKeyring = useKeyringInt != 0
+    p.JumpProfileIDs = splitIDs(jumpIDs)
+    p.ForwardAgent = forwardAgentInt != 0
+    p.Favorite = favoriteInt != 0
+
+    tags, err := s.tags(ctx, p.ID)
+    if err != nil {
+        return profiles.Profile{}, err
+    }
+    p.Tags = tags[p.ID]
     return p, nil
 }
 
@@ -93,11 +144,28 @@ func (s *ProfileStore) Save(ctx context.Context, p profiles.Profile) (string, er
     if p.UseKeyring {
         useKeyringInt = 1
     }
+    forwardAgentInt := 0
+    if p.ForwardAgent {
+        forwardAgentInt = 1
+    }
+
+    favoriteInt := 0
+    if p.Favorite {
+        favoriteInt = 1
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return "", err
+    }
+    defer tx.Rollback()
 
-    _, err := s.db.ExecContext(ctx, `
+    _, err = tx.ExecContext(ctx, `
         INSERT INTO profiles (
-            id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            id, name, group_name, host, port, username, auth_type, private_key_path, use_keyring, known_hosts_policy,
+            jump_profile_ids, proxy_command, reconnect_max_attempts, forward_agent, certificate_path,
+            favorite
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         ON CONFLICT(id) DO UPDATE SET
             name = excluded.name,
             group_name = excluded.group_name,
@@ -107,7 +175,13 @@ func (s *ProfileStore) Save(ctx context.Context, p profiles.Profile) (string, er
             auth_type = excluded.auth_type,
             private_key_path = excluded.private_key_path,
             use_keyring = excluded.use_keyring,
-            known_hosts_policy = excluded.known_hosts_policy
+            known_hosts_policy = excluded.known_hosts_policy,
+            jump_profile_ids = excluded.jump_profile_ids,
+            proxy_command = excluded.proxy_command,
+            reconnect_max_attempts = excluded.reconnect_max_attempts,
+            forward_agent = excluded.forward_agent,
+            certificate_path = excluded.certificate_path,
+            favorite = excluded.favorite
     `,
         p.ID,
         p.Name,
@@ -119,15 +193,138 @@ func (s *ProfileStore) Save(ctx context.Context, p profiles.Profile) (string, er
         p.PrivateKeyPath,
         useKeyringInt,
         p.KnownHostsPolicy,
+        strings.Join(p.JumpProfileIDs, ","),
+        p.ProxyCommand,
+        p.ReconnectMaxAttempts,
+        forwardAgentInt,
+        p.CertificatePath,
+        favoriteInt,
     )
     if err != nil {
         return "", err
     }
 
+    if err := saveTags(ctx, tx, p.ID, p.Tags); err != nil {
+        return "", err
+    }
+    if err := tx.Commit(); err != nil {
+        return "", err
+    }
+
     return p.ID, nil
 }
 
 func (s *ProfileStore) Delete(ctx context.Context, id string) error {
-    _, err := s.db.ExecContext(ctx, "DELETE FROM profiles WHERE id = ?", id)
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, "DELETE FROM profile_tags WHERE profile_id = ?", id); err != nil {
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM profiles WHERE id = ?", id); err != nil {
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM profile_tags)"); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+func (s *ProfileStore) Search(ctx context.Context, query profiles.SearchQuery) ([]profiles.Profile, error) {
+    items, err := s.List(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return profiles.Filter(items, query), nil
+}
+
+func (s *ProfileStore) RecordConnect(ctx context.Context, id string, at time.Time) error {
+    result, err := s.db.ExecContext(ctx,
+        "UPDATE profiles SET last_connected_at = ?, connect_count = connect_count + 1 WHERE id = ?",
+        at.Unix(), id,
+    )
+    if err != nil {
+        return err
+    }
+    affected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if affected == 0 {
+        return common.ErrNotFound
+    }
+    return nil
+}
+
+// tags returns tag names by profile ID, for one profile or, when profileID is
+// empty, for all of them.
+func (s *ProfileStore) tags(ctx context.Context, profileID string) (map[string][]string, error) {
+    query := `
+        SELECT pt.profile_id, t.name
+        FROM profile_tags pt
+        JOIN tags t ON t.id = pt.tag_id
+    `
+    var args []any
+    if profileID != "" {
+        query += " WHERE pt.profile_id = ?"
+        args = append(args, profileID)
+    }
+    query += " ORDER BY t.name"
+
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    tags := map[string][]string{}
+    for rows.Next() {
+        var id, name string
+        if err := rows.Scan(&id, &name); err != nil {
+            return nil, err
+        }
+        tags[id] = append(tags[id], name)
+    }
+    return tags, rows.Err()
+}
+
+// saveTags replaces the tags of a profile, creating tag rows as needed and
+// dropping tags no profile uses any more.
+func saveTags(ctx context.Context, tx *sql.Tx, profileID string, tags []string) error {
+    if _, err := tx.ExecContext(ctx, "DELETE FROM profile_tags WHERE profile_id = ?", profileID); err != nil {
+        return err
+    }
+
+    seen := map[string]bool{}
+    for _, tag := range tags {
+        tag = strings.TrimSpace(tag)
+        key := strings.ToLower(tag)
+        if tag == "" || seen[key] {
+            continue
+        }
+        seen[key] = true
+
+        if _, err := tx.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING", tag); err != nil {
+            return err
+        }
+        if _, err := tx.ExecContext(ctx, `
+            INSERT INTO profile_tags (profile_id, tag_id)
+            SELECT ?, id FROM tags WHERE name = ?
+        `, profileID, tag); err != nil {
+            return err
+        }
+    }
+
+    _, err := tx.ExecContext(ctx, "DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM profile_tags)")
     return err
 }
+
+func splitIDs(value string) []string {
+    if value == "" {
+        return nil
+    }
+    return strings.Split(value, ",")
+}
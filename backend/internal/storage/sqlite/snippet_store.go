@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"goterm/backend/internal/common"
+	"goterm/backend/internal/snippets"
+)
+
+type SnippetStore struct {
+	db *sql.DB
+}
+
+func (s *SnippetStore) List(ctx context.Context) ([]snippets.Snippet, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, name, description, body, params, created_at, updated_at
+        FROM snippets
+        ORDER BY name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []snippets.Snippet
+	for rows.Next() {
+		item, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tags(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Tags = tags[items[i].ID]
+	}
+	return items, nil
+}
+
+func (s *SnippetStore) Get(ctx context.Context, id string) (snippets.Snippet, error) {
+	row := s.db.QueryRowContext(ctx, `
+        SELECT id, name, description, body, params, created_at, updated_at
+        FROM snippets
+        WHERE id = ?
+    `, id)
+
+	item, err := scanSnippet(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return snippets.Snippet{}, common.ErrNotFound
+		}
+		return snippets.Snippet{}, err
+	}
+
+	tags, err := s.tags(ctx, id)
+	if err != nil {
+		return snippets.Snippet{}, err
+	}
+	item.Tags = tags[id]
+	return item, nil
+}
+
+func (s *SnippetStore) Save(ctx context.Context, item snippets.Snippet) (string, error) {
+	if item.ID == "" {
+		id, err := common.NewID()
+		if err != nil {
+			return "", err
+		}
+		item.ID = id
+	}
+
+	params := item.Params
+	if params == nil {
+		params = []snippets.Param{}
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO snippets (id, name, description, body, params, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(id) DO UPDATE SET
+            name = excluded.name,
+            description = excluded.description,
+            body = excluded.body,
+            params = excluded.params,
+            updated_at = excluded.updated_at
+    `,
+		item.ID,
+		item.Name,
+		item.Description,
+		item.Body,
+		string(paramsJSON),
+		now,
+		now,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM snippet_tags WHERE snippet_id = ?", item.ID); err != nil {
+		return "", err
+	}
+	for _, tag := range item.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO snippet_tags (snippet_id, tag) VALUES (?, ?)", item.ID, tag); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return item.ID, nil
+}
+
+func (s *SnippetStore) Delete(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM snippet_tags WHERE snippet_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM snippets WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SnippetStore) tags(ctx context.Context, snippetID string) (map[string][]string, error) {
+	query := "SELECT snippet_id, tag FROM snippet_tags"
+	var args []any
+	if snippetID != "" {
+		query += " WHERE snippet_id = ?"
+		args = append(args, snippetID)
+	}
+	query += " ORDER BY tag"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[string][]string{}
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSnippet(row rowScanner) (snippets.Snippet, error) {
+	var item snippets.Snippet
+	var paramsJSON string
+	if err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Description,
+		&item.Body,
+		&paramsJSON,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	); err != nil {
+		return snippets.Snippet{}, err
+	}
+	if err := json.Unmarshal([]byte(paramsJSON), &item.Params); err != nil {
+		return snippets.Snippet{}, err
+	}
+	if item.Params == nil {
+		item.Params = []snippets.Param{}
+	}
+	return item, nil
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// Params are stored as a JSON array; tags get their own table so they can be
+// queried.
+const snippetSchema = `
+CREATE TABLE IF NOT EXISTS snippets (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    body TEXT NOT NULL,
+    params TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS snippet_tags (
+    snippet_id TEXT NOT NULL,
+    tag TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (snippet_id, tag)
+);
+`
+
+var snippetMigrations = []migration{
+	{version: 1, name: "baseline", up: execMigration(snippetSchema)},
+}
+
+func OpenSnippetStore(path string) (*SnippetStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db, path, snippetMigrations); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SnippetStore{db: db}, nil
+}
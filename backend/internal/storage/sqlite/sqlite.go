@@ -2,6 +2,7 @@ package sqlite
 
 import (
     "database/sql"
+    "fmt"
     "os"
     "path/filepath"
 
@@ -23,6 +24,57 @@ CREATE TABLE IF NOT EXISTS profiles (
 );
 `
 
+type column struct {
+    name       string
+    definition string
+}
+
+// profileColumns were added to the profiles table after the baseline schema.
+var profileColumns = []column{
+    {name: "jump_profile_ids", definition: "TEXT NOT NULL DEFAULT ''"},
+    {name: "proxy_command", definition: "TEXT NOT NULL DEFAULT ''"},
+    {name: "reconnect_max_attempts", definition: "INTEGER NOT NULL DEFAULT 0"},
+    {name: "forward_agent", definition: "INTEGER NOT NULL DEFAULT 0"},
+    {name: "certificate_path", definition: "TEXT NOT NULL DEFAULT ''"},
+}
+
+// tagSchema stores tags once and links them to profiles many-to-many. Tag
+// names are unique regardless of case.
+const tagSchema = `
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+CREATE TABLE IF NOT EXISTS profile_tags (
+    profile_id TEXT NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (profile_id, tag_id)
+);
+`
+
+var profileUsageColumns = []column{
+    {name: "favorite", definition: "INTEGER NOT NULL DEFAULT 0"},
+    {name: "last_connected_at", definition: "INTEGER NOT NULL DEFAULT 0"},
+    {name: "connect_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+}
+
+var profileMigrations = []migration{
+    {version: 1, name: "baseline", up: execMigration(schema)},
+    {version: 2, name: "profile connection options", up: addColumnsMigration("profiles", profileColumns)},
+    {version: 3, name: "tags, favorites and usage", up: func(tx *sql.Tx) error {
+        if err := addColumnsMigration("profiles", profileUsageColumns)(tx); err != nil {
+            return err
+        }
+        return execMigration(tagSchema)(tx)
+    }},
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+    Exec(query string, args ...any) (sql.Result, error)
+    Query(query string, args ...any) (*sql.Rows, error)
+}
+
 func OpenProfileStore(path string) (*ProfileStore, error) {
     if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
         return nil, err
@@ -35,10 +87,46 @@ func OpenProfileStore(path string) (*ProfileStore, error) {
 
     db.SetMaxOpenConns(1)
 
-    if _, err := db.Exec(schema); err != nil {
+    if err := migrate(db, path, profileMigrations); err != nil {
         _ = db.Close()
         return nil, err
     }
 
     return &ProfileStore{db: db}, nil
 }
+
+func ensureColumns(db queryer, table string, columns []column) error {
+    rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+    if err != nil {
+        return err
+    }
+    existing := map[string]bool{}
+    for rows.Next() {
+        var (
+            cid       int
+            name      string
+            colType   string
+            notNull   int
+            dfltValue sql.NullString
+            pk        int
+        )
+        if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+            _ = rows.Close()
+            return err
+        }
+        existing[name] = true
+    }
+    if err := rows.Close(); err != nil {
+        return err
+    }
+
+    for _, col := range columns {
+        if existing[col.name] {
+            continue
+        }
+        if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.definition)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,297 @@
+package terminal
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"goterm/backend/internal/common"
+)
+
+var ErrBroadcastDeclined = errors.New("broadcast to these hosts was not confirmed")
+
+// BroadcastGroup sends input typed into any enabled member to every enabled
+// member. A terminal belongs to at most one group.
+type BroadcastGroup struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Members []BroadcastMember `json:"members"`
+}
+
+type BroadcastMember struct {
+	TermID    string `json:"termId"`
+	SessionID string `json:"sessionId"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// BroadcastGuardFunc is asked before a group gains an enabled member, with
+// the sessions of all enabled members including the new one. Returning false
+// keeps the member out, or disabled.
+type BroadcastGuardFunc func(groupName string, sessionIDs []string) (bool, error)
+
+type broadcast struct {
+	id      string
+	name    string
+	members []*broadcastMember
+}
+
+type broadcastMember struct {
+	termID  string
+	enabled bool
+}
+
+func (h *Hub) SetBroadcastGuard(fn BroadcastGuardFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.guard = fn
+}
+
+func (h *Hub) CreateBroadcast(name string) (BroadcastGroup, error) {
+	id, err := common.NewID()
+	if err != nil {
+		return BroadcastGroup{}, err
+	}
+	h.mu.Lock()
+	group := &broadcast{id: id, name: name}
+	h.broadcasts[id] = group
+	info := h.broadcastInfoLocked(group)
+	h.mu.Unlock()
+
+	h.emitter.Emit("broadcast:state", info)
+	return info, nil
+}
+
+// DeleteBroadcast dissolves the group. Its terminals stay open.
+func (h *Hub) DeleteBroadcast(groupID string) error {
+	h.mu.Lock()
+	group, ok := h.broadcasts[groupID]
+	if ok {
+		for _, member := range group.members {
+			delete(h.memberOf, member.termID)
+		}
+		delete(h.broadcasts, groupID)
+	}
+	h.mu.Unlock()
+	if !ok {
+		return common.ErrNotFound
+	}
+	h.emitter.Emit("broadcast:deleted", groupID)
+	return nil
+}
+
+func (h *Hub) ListBroadcasts() []BroadcastGroup {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	items := make([]BroadcastGroup, 0, len(h.broadcasts))
+	for _, group := range h.broadcasts {
+		items = append(items, h.broadcastInfoLocked(group))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items
+}
+
+// AddToBroadcast adds termID to the group as an enabled member, moving it out
+// of any other group.
+func (h *Hub) AddToBroadcast(groupID, termID string) (BroadcastGroup, error) {
+	if err := h.checkBroadcast(groupID, termID); err != nil {
+		return BroadcastGroup{}, err
+	}
+
+	h.mu.Lock()
+	group, ok := h.broadcasts[groupID]
+	_, open := h.terms[termID]
+	if !ok || !open {
+		h.mu.Unlock()
+		return BroadcastGroup{}, common.ErrNotFound
+	}
+	if current, ok := h.memberOf[termID]; ok && current != groupID {
+		h.removeMemberLocked(current, termID)
+	}
+	if member := findMember(group, termID); member != nil {
+		member.enabled = true
+	} else {
+		group.members = append(group.members, &broadcastMember{termID: termID, enabled: true})
+	}
+	h.memberOf[termID] = groupID
+	info := h.broadcastInfoLocked(group)
+	h.mu.Unlock()
+
+	h.emitter.Emit("broadcast:state", info)
+	return info, nil
+}
+
+func (h *Hub) RemoveFromBroadcast(groupID, termID string) (BroadcastGroup, error) {
+	h.mu.Lock()
+	group, ok := h.broadcasts[groupID]
+	if !ok || findMember(group, termID) == nil {
+		h.mu.Unlock()
+		return BroadcastGroup{}, common.ErrNotFound
+	}
+	h.removeMemberLocked(groupID, termID)
+	info := h.broadcastInfoLocked(group)
+	h.mu.Unlock()
+
+	h.emitter.Emit("broadcast:state", info)
+	return info, nil
+}
+
+// SetBroadcastEnabled toggles whether a member sends and receives broadcast
+// input. A disabled member stays in the group but is typed into on its own.
+func (h *Hub) SetBroadcastEnabled(groupID, termID string, enabled bool) (BroadcastGroup, error) {
+	if enabled {
+		if err := h.checkBroadcast(groupID, termID); err != nil {
+			return BroadcastGroup{}, err
+		}
+	}
+
+	h.mu.Lock()
+	group, ok := h.broadcasts[groupID]
+	var member *broadcastMember
+	if ok {
+		member = findMember(group, termID)
+	}
+	if member == nil {
+		h.mu.Unlock()
+		return BroadcastGroup{}, common.ErrNotFound
+	}
+	member.enabled = enabled
+	info := h.broadcastInfoLocked(group)
+	h.mu.Unlock()
+
+	h.emitter.Emit("broadcast:state", info)
+	return info, nil
+}
+
+// checkBroadcast asks the guard whether termID may join the enabled members
+// of the group. The guard may block on the user, so it runs unlocked.
+func (h *Hub) checkBroadcast(groupID, termID string) error {
+	h.mu.Lock()
+	group, ok := h.broadcasts[groupID]
+	term, open := h.terms[termID]
+	if !ok || !open {
+		h.mu.Unlock()
+		return common.ErrNotFound
+	}
+	guard := h.guard
+	name := group.name
+	sessionIDs := []string{term.SessionID}
+	for _, member := range group.members {
+		if !member.enabled || member.termID == termID {
+			continue
+		}
+		if other, ok := h.terms[member.termID]; ok {
+			sessionIDs = append(sessionIDs, other.SessionID)
+		}
+	}
+	h.mu.Unlock()
+
+	if guard == nil {
+		return nil
+	}
+	allowed, err := guard(name, sessionIDs)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrBroadcastDeclined
+	}
+	return nil
+}
+
+// targets returns the terminals input to termID goes to: every enabled member
+// of its broadcast group, or just termID itself.
+func (h *Hub) targets(termID string) ([]*Terminal, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	term, ok := h.terms[termID]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+
+	groupID, ok := h.memberOf[termID]
+	if !ok {
+		return []*Terminal{term}, nil
+	}
+	group := h.broadcasts[groupID]
+	if member := findMember(group, termID); member == nil || !member.enabled {
+		return []*Terminal{term}, nil
+	}
+
+	targets := []*Terminal{}
+	for _, member := range group.members {
+		if !member.enabled {
+			continue
+		}
+		if other, ok := h.terms[member.termID]; ok {
+			targets = append(targets, other)
+		}
+	}
+	return targets, nil
+}
+
+func (h *Hub) writeAll(targets []*Terminal, data string) error {
+	var firstErr error
+	for _, term := range targets {
+		h.mu.Lock()
+		rec := term.rec
+		h.mu.Unlock()
+		if rec != nil {
+			rec.input(data)
+		}
+		if _, err := io.WriteString(term.Stdin, data); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// leaveBroadcastLocked drops a closed terminal from its group and returns the
+// updated group, if any.
+func (h *Hub) leaveBroadcastLocked(termID string) *BroadcastGroup {
+	groupID, ok := h.memberOf[termID]
+	if !ok {
+		return nil
+	}
+	h.removeMemberLocked(groupID, termID)
+	group, ok := h.broadcasts[groupID]
+	if !ok {
+		return nil
+	}
+	info := h.broadcastInfoLocked(group)
+	return &info
+}
+
+func (h *Hub) removeMemberLocked(groupID, termID string) {
+	delete(h.memberOf, termID)
+	group, ok := h.broadcasts[groupID]
+	if !ok {
+		return
+	}
+	for i, member := range group.members {
+		if member.termID == termID {
+			group.members = append(group.members[:i], group.members[i+1:]...)
+			return
+		}
+	}
+}
+
+func (h *Hub) broadcastInfoLocked(group *broadcast) BroadcastGroup {
+	info := BroadcastGroup{ID: group.id, Name: group.name, Members: []BroadcastMember{}}
+	for _, member := range group.members {
+		item := BroadcastMember{TermID: member.termID, Enabled: member.enabled}
+		if term, ok := h.terms[member.termID]; ok {
+			item.SessionID = term.SessionID
+		}
+		info.Members = append(info.Members, item)
+	}
+	return info
+}
+
+func findMember(group *broadcast, termID string) *broadcastMember {
+	for _, member := range group.members {
+		if member.termID == termID {
+			return member
+		}
+	}
+	return nil
+}
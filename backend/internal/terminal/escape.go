@@ -0,0 +1,67 @@
+package terminal
+
+import "strings"
+
+const (
+	escapeNone = iota
+	escapeStart
+	escapeCSI
+	escapeString
+	escapeStringEnd
+	escapeCharset
+)
+
+// escapeStripper removes escape sequences from terminal output and keeps
+// plain text and control characters. It keeps its state between calls since
+// a sequence may be split across chunks.
+type escapeStripper struct {
+	state int
+}
+
+func (e *escapeStripper) strip(data string) string {
+	if e.state == escapeNone && !strings.ContainsRune(data, 0x1b) {
+		return data
+	}
+
+	var out strings.Builder
+	for _, r := range data {
+		switch e.state {
+		case escapeStart:
+			switch r {
+			case '[':
+				e.state = escapeCSI
+			case ']', 'P', '_', '^':
+				e.state = escapeString
+			case '(', ')', '*', '+', '#', '%':
+				e.state = escapeCharset
+			default:
+				e.state = escapeNone
+			}
+		case escapeCSI:
+			if r >= 0x40 && r <= 0x7e {
+				e.state = escapeNone
+			}
+		case escapeString:
+			switch r {
+			case 0x07:
+				e.state = escapeNone
+			case 0x1b:
+				e.state = escapeStringEnd
+			}
+		case escapeStringEnd:
+			e.state = escapeString
+			if r == '\\' {
+				e.state = escapeNone
+			}
+		case escapeCharset:
+			e.state = escapeNone
+		default:
+			if r == 0x1b {
+				e.state = escapeStart
+				continue
+			}
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
@@ -3,15 +3,22 @@ package terminal
 import (
     "errors"
     "io"
+    "sort"
     "sync"
+    "time"
+    "unicode/utf8"
 
     "golang.org/x/crypto/ssh"
+    "golang.org/x/crypto/ssh/agent"
 
     "goterm/backend/internal/common"
 )
 
+var errAlreadyRecording = errors.New("terminal is already being recorded")
+
 type ClientProvider interface {
     GetClient(sessionID string) (*ssh.Client, error)
+    AgentForwarding(sessionID string) bool
 }
 
 type Terminal struct {
@@ -19,11 +26,36 @@ type Terminal struct {
     SessionID string
     SSH       *ssh.Session
     Stdin     io.WriteCloser
+    Cols      int
+    Rows      int
+    OpenedAt  time.Time
+    // Attached is false while no view shows the terminal. It keeps running
+    // and buffering output until Close.
+    Attached  bool
+
+    rec      *recorder
+    output   *scrollback
+    triggers *triggerEngine
+    marks    []Mark
+}
+
+// TerminalInfo describes an open terminal, attached to a view or not.
+type TerminalInfo struct {
+    ID        string `json:"id"`
+    SessionID string `json:"sessionId"`
+    Cols      int    `json:"cols"`
+    Rows      int    `json:"rows"`
+    Attached  bool   `json:"attached"`
+    Recording bool   `json:"recording"`
+    OpenedAt  int64  `json:"openedAt"`
 }
 
+// DataEvent carries terminal output. Seq orders the output of one terminal
+// so a view can skip what a snapshot already contained.
 type DataEvent struct {
     TermID string `json:"termId"`
     Chunk  string `json:"chunk"`
+    Seq    uint64 `json:"seq,omitempty"`
 }
 
 type ExitEvent struct {
@@ -31,12 +63,26 @@ type ExitEvent struct {
     Code   int    `json:"code"`
 }
 
+// TriggerFunc returns the trigger rules for terminals on sessionID.
+type TriggerFunc func(sessionID string) []TriggerRule
+
+// AutoRecordFunc decides whether a new terminal on sessionID is recorded
+// from its first byte.
+type AutoRecordFunc func(sessionID string) (RecordOptions, bool)
+
 type Hub struct {
     provider ClientProvider
     emitter  common.Emitter
 
-    mu    sync.Mutex
-    terms map[string]*Terminal
+    mu         sync.Mutex
+    terms      map[string]*Terminal
+    recordDir  string
+    autoRecord AutoRecordFunc
+    scrollback int
+    guard      BroadcastGuardFunc
+    triggerFn  TriggerFunc
+    broadcasts map[string]*broadcast
+    memberOf   map[string]string
 }
 
 func NewHub(provider ClientProvider, emitter common.Emitter) *Hub {
@@ -44,12 +90,61 @@ func NewHub(provider ClientProvider, emitter common.Emitter) *Hub {
         emitter = common.NopEmitter{}
     }
     return &Hub{
-        provider: provider,
-        emitter:  emitter,
-        terms:    map[string]*Terminal{},
+        provider:   provider,
+        emitter:    emitter,
+        terms:      map[string]*Terminal{},
+        scrollback: defaultScrollback,
+        broadcasts: map[string]*broadcast{},
+        memberOf:   map[string]string{},
     }
 }
 
+// SetScrollbackLimit sets how many bytes of output are kept per terminal for
+// terminals opened afterwards. Zero disables the buffer.
+func (h *Hub) SetScrollbackLimit(limit int) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.scrollback = limit
+}
+
+// SetRecordingDir sets where recordings are written.
+func (h *Hub) SetRecordingDir(dir string) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.recordDir = dir
+}
+
+func (h *Hub) SetTriggerFunc(fn TriggerFunc) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.triggerFn = fn
+}
+
+// ReloadTriggers applies changed trigger rules to the open terminals.
+func (h *Hub) ReloadTriggers() {
+    h.mu.Lock()
+    fn := h.triggerFn
+    terms := make([]*Terminal, 0, len(h.terms))
+    for _, term := range h.terms {
+        terms = append(terms, term)
+    }
+    h.mu.Unlock()
+
+    for _, term := range terms {
+        var rules []TriggerRule
+        if fn != nil {
+            rules = fn(term.SessionID)
+        }
+        term.triggers.load(rules)
+    }
+}
+
+func (h *Hub) SetAutoRecordFunc(fn AutoRecordFunc) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.autoRecord = fn
+}
+
 func (h *Hub) Open(sessionID string, cols, rows int) (string, error) {
     client, err := h.provider.GetClient(sessionID)
     if err != nil {
@@ -74,6 +169,13 @@ func (h *Hub) Open(sessionID string, cols, rows int) (string, error) {
         ssh.TTY_OP_OSPEED: 14400,
     }
 
+    if h.provider.AgentForwarding(sessionID) {
+        if err := agent.RequestAgentForwarding(sshSession); err != nil {
+            _ = sshSession.Close()
+            return "", err
+        }
+    }
+
     if err := sshSession.RequestPty("xterm-256color", rows, cols, modes); err != nil {
         _ = sshSession.Close()
         return "", err
@@ -113,6 +215,30 @@ func (h *Hub) Open(sessionID string, cols, rows int) (string, error) {
         SessionID: sessionID,
         SSH:       sshSession,
         Stdin:     stdin,
+        Cols:      cols,
+        Rows:      rows,
+        OpenedAt:  time.Now(),
+        Attached:  true,
+    }
+
+    h.mu.Lock()
+    autoRecord, recordDir, triggerFn := h.autoRecord, h.recordDir, h.triggerFn
+    term.output = newScrollback(h.scrollback)
+    h.mu.Unlock()
+    var rules []TriggerRule
+    if triggerFn != nil {
+        rules = triggerFn(sessionID)
+    }
+    term.triggers = newTriggerEngine(rules)
+    if autoRecord != nil {
+        if opts, ok := autoRecord(sessionID); ok {
+            rec, err := startRecorder(recordDir, id, cols, rows, opts)
+            if err != nil {
+                _ = sshSession.Close()
+                return "", err
+            }
+            term.rec = rec
+        }
     }
 
     h.mu.Lock()
@@ -126,13 +252,14 @@ func (h *Hub) Open(sessionID string, cols, rows int) (string, error) {
     return id, nil
 }
 
+// Write sends data to the terminal, or to every enabled member of its
+// broadcast group.
 func (h *Hub) Write(termID string, data string) error {
-    term, err := h.get(termID)
+    targets, err := h.targets(termID)
     if err != nil {
         return err
     }
-    _, err = io.WriteString(term.Stdin, data)
-    return err
+    return h.writeAll(targets, data)
 }
 
 func (h *Hub) Resize(termID string, cols, rows int) error {
@@ -143,7 +270,144 @@ func (h *Hub) Resize(termID string, cols, rows int) error {
     if cols <= 0 || rows <= 0 {
         return errors.New("invalid terminal size")
     }
-    return term.SSH.WindowChange(rows, cols)
+    if err := term.SSH.WindowChange(rows, cols); err != nil {
+        return err
+    }
+
+    h.mu.Lock()
+    term.Cols, term.Rows = cols, rows
+    rec := term.rec
+    h.mu.Unlock()
+    if rec != nil {
+        rec.resize(cols, rows)
+    }
+    return nil
+}
+
+// List returns the open terminals, including detached ones.
+func (h *Hub) List() []TerminalInfo {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    items := make([]TerminalInfo, 0, len(h.terms))
+    for _, term := range h.terms {
+        items = append(items, h.infoLocked(term))
+    }
+    sort.Slice(items, func(i, j int) bool { return items[i].OpenedAt < items[j].OpenedAt })
+    return items
+}
+
+// Attach marks the terminal as shown by a view and returns its buffered
+// output so the view can rebuild the screen.
+func (h *Hub) Attach(termID string) (Snapshot, error) {
+    h.mu.Lock()
+    term, ok := h.terms[termID]
+    if ok {
+        term.Attached = true
+    }
+    h.mu.Unlock()
+    if !ok {
+        return Snapshot{}, common.ErrNotFound
+    }
+    return h.Snapshot(termID)
+}
+
+// Detach marks the terminal as not shown anywhere. The shell keeps running.
+func (h *Hub) Detach(termID string) error {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    term, ok := h.terms[termID]
+    if !ok {
+        return common.ErrNotFound
+    }
+    term.Attached = false
+    return nil
+}
+
+func (h *Hub) Snapshot(termID string) (Snapshot, error) {
+    h.mu.Lock()
+    term, ok := h.terms[termID]
+    var snapshot Snapshot
+    if ok {
+        snapshot = Snapshot{
+            TermID:    term.ID,
+            SessionID: term.SessionID,
+            Cols:      term.Cols,
+            Rows:      term.Rows,
+        }
+    }
+    h.mu.Unlock()
+    if !ok {
+        return Snapshot{}, common.ErrNotFound
+    }
+    snapshot.Data, snapshot.Seq, snapshot.Truncated = term.output.snapshot()
+    return snapshot, nil
+}
+
+func (h *Hub) infoLocked(term *Terminal) TerminalInfo {
+    return TerminalInfo{
+        ID:        term.ID,
+        SessionID: term.SessionID,
+        Cols:      term.Cols,
+        Rows:      term.Rows,
+        Attached:  term.Attached,
+        Recording: term.rec != nil,
+        OpenedAt:  term.OpenedAt.Unix(),
+    }
+}
+
+// StartRecording records the terminal's output, and its input when asked,
+// until StopRecording or until the terminal closes.
+func (h *Hub) StartRecording(termID string, opts RecordOptions) (Recording, error) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    term, ok := h.terms[termID]
+    if !ok {
+        return Recording{}, common.ErrNotFound
+    }
+    if term.rec != nil {
+        return Recording{}, errAlreadyRecording
+    }
+    rec, err := startRecorder(h.recordDir, termID, term.Cols, term.Rows, opts)
+    if err != nil {
+        return Recording{}, err
+    }
+    term.rec = rec
+    return rec.status(), nil
+}
+
+func (h *Hub) StopRecording(termID string) (Recording, error) {
+    h.mu.Lock()
+    term, ok := h.terms[termID]
+    var rec *recorder
+    if ok {
+        rec, term.rec = term.rec, nil
+    }
+    h.mu.Unlock()
+    if !ok {
+        return Recording{}, common.ErrNotFound
+    }
+    if rec == nil {
+        return Recording{}, errors.New("terminal is not being recorded")
+    }
+    return rec.stop()
+}
+
+// ActiveRecordings lists the recordings still being written.
+func (h *Hub) ActiveRecordings() []Recording {
+    h.mu.Lock()
+    recs := make([]*recorder, 0, len(h.terms))
+    for _, term := range h.terms {
+        if term.rec != nil {
+            recs = append(recs, term.rec)
+        }
+    }
+    h.mu.Unlock()
+
+    items := make([]Recording, 0, len(recs))
+    for _, rec := range recs {
+        items = append(items, rec.status())
+    }
+    return items
 }
 
 func (h *Hub) Close(termID string) error {
@@ -154,7 +418,16 @@ func (h *Hub) Close(termID string) error {
 
     h.mu.Lock()
     delete(h.terms, termID)
+    rec := term.rec
+    term.rec = nil
+    group := h.leaveBroadcastLocked(termID)
     h.mu.Unlock()
+    if rec != nil {
+        _, _ = rec.stop()
+    }
+    if group != nil {
+        h.emitter.Emit("broadcast:state", *group)
+    }
 
     return term.SSH.Close()
 }
@@ -169,16 +442,53 @@ func (h *Hub) get(termID string) (*Terminal, error) {
     return term, nil
 }
 
+func (h *Hub) output(termID string) (*scrollback, *recorder, *triggerEngine) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if term, ok := h.terms[termID]; ok {
+        return term.output, term.rec, term.triggers
+    }
+    return nil, nil, nil
+}
+
 func (h *Hub) stream(termID string, reader io.Reader) {
     buf := make([]byte, 4096)
+    pending := 0
+    var source triggerStream
     for {
-        n, err := reader.Read(buf)
-        if n > 0 {
+        n, err := reader.Read(buf[pending:])
+        n += pending
+        // A character split across two reads is held back until the rest
+        // arrives, so every chunk is valid UTF-8 for the view and the
+        // recording.
+        complete := n
+        if err == nil {
+            complete = completeRunes(buf[:n])
+        }
+        if complete > 0 {
+            var seq uint64
+            chunk := string(buf[:complete])
+            output, rec, triggers := h.output(termID)
+            if output != nil {
+                seq = output.write(buf[:complete])
+            }
+            if rec != nil {
+                rec.output(chunk)
+            }
             h.emitter.Emit("terminal:data", DataEvent{
                 TermID: termID,
-                Chunk:  string(buf[:n]),
+                Chunk:  chunk,
+                Seq:    seq,
             })
+            if triggers != nil {
+                // Actions write to the terminal, so they must not hold up
+                // the reader.
+                if hits := triggers.scan(&source, chunk, time.Now()); len(hits) > 0 {
+                    go h.fire(termID, seq, hits)
+                }
+            }
         }
+        pending = copy(buf, buf[complete:n])
         if err != nil {
             if err != io.EOF {
                 h.emitter.Emit("terminal:data", DataEvent{
@@ -191,6 +501,20 @@ func (h *Hub) stream(termID string, reader io.Reader) {
     }
 }
 
+// completeRunes returns the length of p without a trailing incomplete UTF-8
+// sequence.
+func completeRunes(p []byte) int {
+    for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax+1; i-- {
+        if utf8.RuneStart(p[i]) {
+            if !utf8.FullRune(p[i:]) {
+                return i
+            }
+            break
+        }
+    }
+    return len(p)
+}
+
 func (h *Hub) wait(termID string, sshSession *ssh.Session) {
     err := sshSession.Wait()
     code := 0
@@ -205,6 +529,17 @@ func (h *Hub) wait(termID string, sshSession *ssh.Session) {
     h.emitter.Emit("terminal:exit", ExitEvent{TermID: termID, Code: code})
 
     h.mu.Lock()
+    var rec *recorder
+    if term, ok := h.terms[termID]; ok {
+        rec, term.rec = term.rec, nil
+    }
     delete(h.terms, termID)
+    group := h.leaveBroadcastLocked(termID)
     h.mu.Unlock()
+    if rec != nil {
+        _, _ = rec.stop()
+    }
+    if group != nil {
+        h.emitter.Emit("broadcast:state", *group)
+    }
 }
@@ -0,0 +1,501 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"goterm/backend/internal/common"
+)
+
+const (
+	// resetSequence clears the screen before a seek replays earlier output.
+	resetSequence = "\x1bc"
+	seekChunkSize = 64 * 1024
+	maxSpeed      = 64
+)
+
+type castEvent struct {
+	Time float64
+	Kind string
+	Data string
+}
+
+// PlaybackState is emitted as "playback:state" whenever a playback starts,
+// pauses, seeks or ends. Output is emitted as "terminal:data" with the
+// playback ID in place of a terminal ID.
+type PlaybackState struct {
+	ID        string  `json:"id"`
+	Recording string  `json:"recording"`
+	Cols      int     `json:"cols"`
+	Rows      int     `json:"rows"`
+	Position  float64 `json:"position"`
+	Duration  float64 `json:"duration"`
+	Speed     float64 `json:"speed"`
+	Playing   bool    `json:"playing"`
+	Finished  bool    `json:"finished"`
+}
+
+// TextLine is one line of a recording's output with the time it started.
+type TextLine struct {
+	Time float64 `json:"time"`
+	Text string  `json:"text"`
+}
+
+// Player replays asciicast v2 recordings.
+type Player struct {
+	emitter common.Emitter
+
+	mu        sync.Mutex
+	playbacks map[string]*playback
+}
+
+type playback struct {
+	id        string
+	recording string
+	width     int
+	height    int
+	events    []castEvent
+	emitter   common.Emitter
+
+	mu       sync.Mutex
+	cols     int
+	rows     int
+	next     int
+	base     float64
+	baseWall time.Time
+	speed    float64
+	playing  bool
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+func NewPlayer(emitter common.Emitter) *Player {
+	if emitter == nil {
+		emitter = common.NopEmitter{}
+	}
+	return &Player{emitter: emitter, playbacks: map[string]*playback{}}
+}
+
+// Open loads the recording at path and starts playing it at speed; zero means
+// real time.
+func (p *Player) Open(path string, speed float64) (PlaybackState, error) {
+	header, events, err := readCast(path)
+	if err != nil {
+		return PlaybackState{}, err
+	}
+	if speed, err = checkSpeed(speed); err != nil {
+		return PlaybackState{}, err
+	}
+	id, err := common.NewID()
+	if err != nil {
+		return PlaybackState{}, err
+	}
+
+	pb := &playback{
+		id:        id,
+		recording: strings.TrimSuffix(filepath.Base(path), recordingExt),
+		width:     header.Width,
+		height:    header.Height,
+		cols:      header.Width,
+		rows:      header.Height,
+		events:    events,
+		emitter:   p.emitter,
+		speed:     speed,
+		playing:   true,
+		baseWall:  time.Now(),
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	p.playbacks[id] = pb
+	p.mu.Unlock()
+
+	state := pb.state()
+	p.emitter.Emit("playback:state", state)
+	go pb.run()
+	return state, nil
+}
+
+func (p *Player) Pause(id string) (PlaybackState, error) {
+	return p.update(id, func(pb *playback) {
+		if pb.playing {
+			pb.base = pb.positionLocked()
+			pb.playing = false
+		}
+	})
+}
+
+// Resume continues a paused playback. A finished playback starts over.
+func (p *Player) Resume(id string) (PlaybackState, error) {
+	return p.update(id, func(pb *playback) {
+		if pb.next >= len(pb.events) {
+			pb.seekLocked(0)
+		}
+		pb.playing = true
+		pb.baseWall = time.Now()
+	})
+}
+
+// Seek jumps to at seconds into the recording. The screen is reset and all
+// output up to that point replayed at once, so the terminal ends up in the
+// state it had at that moment.
+func (p *Player) Seek(id string, at float64) (PlaybackState, error) {
+	return p.update(id, func(pb *playback) {
+		pb.seekLocked(at)
+	})
+}
+
+func (p *Player) SetSpeed(id string, speed float64) (PlaybackState, error) {
+	speed, err := checkSpeed(speed)
+	if err != nil {
+		return PlaybackState{}, err
+	}
+	return p.update(id, func(pb *playback) {
+		pb.base = pb.positionLocked()
+		pb.baseWall = time.Now()
+		pb.speed = speed
+	})
+}
+
+func (p *Player) Close(id string) error {
+	p.mu.Lock()
+	pb, ok := p.playbacks[id]
+	delete(p.playbacks, id)
+	p.mu.Unlock()
+	if !ok {
+		return common.ErrNotFound
+	}
+	close(pb.done)
+	return nil
+}
+
+func (p *Player) update(id string, fn func(*playback)) (PlaybackState, error) {
+	p.mu.Lock()
+	pb, ok := p.playbacks[id]
+	p.mu.Unlock()
+	if !ok {
+		return PlaybackState{}, common.ErrNotFound
+	}
+
+	pb.mu.Lock()
+	fn(pb)
+	state := pb.stateLocked()
+	pb.mu.Unlock()
+
+	pb.signal()
+	p.emitter.Emit("playback:state", state)
+	return state, nil
+}
+
+func (pb *playback) run() {
+	for {
+		pb.mu.Lock()
+		if !pb.playing || pb.next >= len(pb.events) {
+			pb.mu.Unlock()
+			select {
+			case <-pb.wake:
+				continue
+			case <-pb.done:
+				return
+			}
+		}
+		event := pb.events[pb.next]
+		delay := time.Duration((event.Time - pb.positionLocked()) / pb.speed * float64(time.Second))
+		pb.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-pb.wake:
+			timer.Stop()
+			continue
+		case <-pb.done:
+			timer.Stop()
+			return
+		}
+
+		pb.mu.Lock()
+		// A control call may have moved the playback while the timer ran.
+		if !pb.playing || pb.next >= len(pb.events) || pb.events[pb.next] != event {
+			pb.mu.Unlock()
+			continue
+		}
+		pb.emitLocked(event)
+		pb.next++
+		var finished *PlaybackState
+		if pb.next >= len(pb.events) {
+			pb.base = pb.duration()
+			pb.playing = false
+			state := pb.stateLocked()
+			finished = &state
+		}
+		pb.mu.Unlock()
+
+		if finished != nil {
+			pb.emitter.Emit("playback:state", *finished)
+		}
+	}
+}
+
+func (pb *playback) emitLocked(event castEvent) {
+	switch event.Kind {
+	case "o":
+		pb.emitter.Emit("terminal:data", DataEvent{TermID: pb.id, Chunk: event.Data})
+	case "r":
+		if cols, rows, ok := parseSize(event.Data); ok {
+			pb.cols, pb.rows = cols, rows
+			pb.emitter.Emit("playback:state", pb.stateLocked())
+		}
+	}
+}
+
+func (pb *playback) seekLocked(at float64) {
+	if at < 0 {
+		at = 0
+	}
+	if duration := pb.duration(); at > duration {
+		at = duration
+	}
+
+	next := sort.Search(len(pb.events), func(i int) bool { return pb.events[i].Time > at })
+
+	pb.cols, pb.rows = pb.width, pb.height
+	var screen strings.Builder
+	screen.WriteString(resetSequence)
+	for _, event := range pb.events[:next] {
+		switch event.Kind {
+		case "o":
+			screen.WriteString(event.Data)
+		case "r":
+			if cols, rows, ok := parseSize(event.Data); ok {
+				pb.cols, pb.rows = cols, rows
+			}
+		}
+	}
+	data := screen.String()
+	for len(data) > 0 {
+		n := min(seekChunkSize, len(data))
+		// Do not split a multi-byte character between two events.
+		for n < len(data) && n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		pb.emitter.Emit("terminal:data", DataEvent{TermID: pb.id, Chunk: data[:n]})
+		data = data[n:]
+	}
+
+	pb.next = next
+	pb.base = at
+	pb.baseWall = time.Now()
+}
+
+func (pb *playback) positionLocked() float64 {
+	if !pb.playing {
+		return pb.base
+	}
+	position := pb.base + time.Since(pb.baseWall).Seconds()*pb.speed
+	if duration := pb.duration(); position > duration {
+		return duration
+	}
+	return position
+}
+
+func (pb *playback) duration() float64 {
+	if len(pb.events) == 0 {
+		return 0
+	}
+	return pb.events[len(pb.events)-1].Time
+}
+
+func (pb *playback) signal() {
+	select {
+	case pb.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (pb *playback) state() PlaybackState {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.stateLocked()
+}
+
+func (pb *playback) stateLocked() PlaybackState {
+	return PlaybackState{
+		ID:        pb.id,
+		Recording: pb.recording,
+		Cols:      pb.cols,
+		Rows:      pb.rows,
+		Position:  pb.positionLocked(),
+		Duration:  pb.duration(),
+		Speed:     pb.speed,
+		Playing:   pb.playing,
+		Finished:  pb.next >= len(pb.events),
+	}
+}
+
+// RecordingText returns the output of a recording as plain text lines with
+// escape sequences removed, for searching and jumping to a match.
+func RecordingText(path string) ([]TextLine, error) {
+	_, events, err := readCast(path)
+	if err != nil {
+		return nil, err
+	}
+	var text textExtractor
+	for _, event := range events {
+		if event.Kind == "o" {
+			text.write(event.Time, event.Data)
+		}
+	}
+	return text.finish(), nil
+}
+
+// textExtractor turns terminal output into plain text lines, applying
+// carriage returns and backspaces the way a screen would.
+type textExtractor struct {
+	lines   []TextLine
+	line    []rune
+	started float64
+	escapes escapeStripper
+	cr      bool
+}
+
+func (t *textExtractor) write(at float64, data string) {
+	for _, r := range t.escapes.strip(data) {
+		if t.cr {
+			t.cr = false
+			if r != '\n' {
+				// A lone carriage return redraws the line, as progress bars do.
+				t.line = t.line[:0]
+			}
+		}
+
+		switch {
+		case r == '\r':
+			t.cr = true
+		case r == '\n':
+			t.flush()
+		case r == '\b':
+			if len(t.line) > 0 {
+				t.line = t.line[:len(t.line)-1]
+			}
+		case r == '\t':
+			t.add(at, r)
+		case r < 0x20 || r == 0x7f:
+		default:
+			t.add(at, r)
+		}
+	}
+}
+
+func (t *textExtractor) add(at float64, r rune) {
+	if len(t.line) == 0 {
+		t.started = at
+	}
+	t.line = append(t.line, r)
+}
+
+func (t *textExtractor) flush() {
+	if text := strings.TrimRight(string(t.line), " \t"); text != "" {
+		t.lines = append(t.lines, TextLine{Time: t.started, Text: text})
+	}
+	t.line = t.line[:0]
+}
+
+func (t *textExtractor) finish() []TextLine {
+	t.flush()
+	if t.lines == nil {
+		return []TextLine{}
+	}
+	return t.lines
+}
+
+// readCast loads the header and the output and resize events of an asciicast
+// v2 file. Input and marker events are not needed for playback.
+func readCast(path string) (castHeader, []castEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return castHeader{}, nil, common.ErrNotFound
+		}
+		return castHeader{}, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var header castHeader
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return castHeader{}, nil, err
+		}
+		return castHeader{}, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return castHeader{}, nil, fmt.Errorf("parse recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return castHeader{}, nil, errors.New("unsupported asciicast version")
+	}
+
+	events := []castEvent{}
+	last := 0.0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var raw []json.RawMessage
+		if err := json.Unmarshal(line, &raw); err != nil || len(raw) != 3 {
+			// A recording cut off by a crash may end in a partial line.
+			continue
+		}
+		var event castEvent
+		if json.Unmarshal(raw[0], &event.Time) != nil ||
+			json.Unmarshal(raw[1], &event.Kind) != nil ||
+			json.Unmarshal(raw[2], &event.Data) != nil {
+			continue
+		}
+		if event.Kind != "o" && event.Kind != "r" {
+			continue
+		}
+		// Keep time monotonic so seeking can binary search.
+		if event.Time < last {
+			event.Time = last
+		}
+		last = event.Time
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return castHeader{}, nil, err
+	}
+	return header, events, nil
+}
+
+func parseSize(value string) (int, int, bool) {
+	var cols, rows int
+	if _, err := fmt.Sscanf(value, "%dx%d", &cols, &rows); err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
+
+func checkSpeed(speed float64) (float64, error) {
+	if speed == 0 {
+		return 1, nil
+	}
+	if speed < 0 || speed > maxSpeed {
+		return 0, fmt.Errorf("playback speed must be between 0 and %d", maxSpeed)
+	}
+	return speed, nil
+}
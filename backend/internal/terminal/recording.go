@@ -0,0 +1,318 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goterm/backend/internal/common"
+)
+
+const recordingExt = ".cast"
+
+// Recording describes an asciicast v2 file. Duration is the time of the last
+// event in seconds.
+type Recording struct {
+	ID        string  `json:"id"`
+	TermID    string  `json:"termId"`
+	Title     string  `json:"title"`
+	Path      string  `json:"path"`
+	Cols      int     `json:"cols"`
+	Rows      int     `json:"rows"`
+	Input     bool    `json:"input"`
+	StartedAt int64   `json:"startedAt"`
+	Duration  float64 `json:"duration"`
+	Size      int64   `json:"size"`
+	Active    bool    `json:"active"`
+}
+
+type RecordOptions struct {
+	Title string `json:"title"`
+	// Input also records what was typed. It may capture passwords entered at
+	// prompts, so it is off unless asked for.
+	Input bool `json:"input"`
+}
+
+// castHeader is the first line of an asciicast v2 file. GoTerm keeps its own
+// fields under the goterm key so other players ignore them.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	GoTerm    *castMeta         `json:"goterm,omitempty"`
+}
+
+type castMeta struct {
+	TermID string `json:"termId"`
+	Input  bool   `json:"input"`
+}
+
+// recorder appends events to one recording. Every event is written straight
+// to the file so a crash loses nothing already shown on screen.
+type recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	started time.Time
+	info    Recording
+}
+
+func startRecorder(dir, termID string, cols, rows int, opts RecordOptions) (*recorder, error) {
+	if dir == "" {
+		return nil, errors.New("recording directory not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	base := fmt.Sprintf("%s-%s", started.Format("20060102-150405"), shortID(termID))
+	// A recording restarted within the same second gets a numbered ID.
+	id, path := base, ""
+	var file *os.File
+	for n := 2; ; n++ {
+		path = filepath.Join(dir, id+recordingExt)
+		var err error
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) || n > 100 {
+			return nil, err
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: started.Unix(),
+		Title:     opts.Title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+		GoTerm:    &castMeta{TermID: termID, Input: opts.Input},
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &recorder{
+		file:    file,
+		started: started,
+		info: Recording{
+			ID:        id,
+			TermID:    termID,
+			Title:     opts.Title,
+			Path:      path,
+			Cols:      cols,
+			Rows:      rows,
+			Input:     opts.Input,
+			StartedAt: started.Unix(),
+			Active:    true,
+		},
+	}, nil
+}
+
+func (r *recorder) output(data string) {
+	r.event("o", data)
+}
+
+func (r *recorder) input(data string) {
+	if r.info.Input {
+		r.event("i", data)
+	}
+}
+
+func (r *recorder) resize(cols, rows int) {
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// marker adds an asciicast marker, which players show as a chapter.
+func (r *recorder) marker(label string) {
+	r.event("m", label)
+}
+
+func (r *recorder) event(kind, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	elapsed := time.Since(r.started).Seconds()
+	line, err := json.Marshal([]any{json.Number(fmt.Sprintf("%.6f", elapsed)), kind, data})
+	if err != nil {
+		return
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return
+	}
+	r.info.Duration = elapsed
+}
+
+func (r *recorder) stop() (Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := r.info
+	info.Active = false
+	if r.file == nil {
+		return info, nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	if stat, statErr := os.Stat(info.Path); statErr == nil {
+		info.Size = stat.Size()
+	}
+	return info, err
+}
+
+func (r *recorder) status() Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := r.info
+	if stat, err := os.Stat(info.Path); err == nil {
+		info.Size = stat.Size()
+	}
+	return info
+}
+
+// ListRecordings returns the recordings in dir, newest first.
+func ListRecordings(dir string) ([]Recording, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Recording{}, nil
+		}
+		return nil, err
+	}
+
+	items := []Recording{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordingExt) {
+			continue
+		}
+		item, err := readRecording(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].StartedAt != items[j].StartedAt {
+			return items[i].StartedAt > items[j].StartedAt
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+
+// RecordingPath resolves a recording ID to its file in dir.
+func RecordingPath(dir, id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", common.ErrNotFound
+	}
+	path := filepath.Join(dir, id+recordingExt)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", common.ErrNotFound
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+func DeleteRecording(dir, id string) error {
+	path, err := RecordingPath(dir, id)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func readRecording(path string) (Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Recording{}, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Recording{}, err
+	}
+	var header castHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return Recording{}, err
+	}
+	if header.Version != 2 {
+		return Recording{}, errors.New("unsupported asciicast version")
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return Recording{}, err
+	}
+	item := Recording{
+		ID:        strings.TrimSuffix(filepath.Base(path), recordingExt),
+		Title:     header.Title,
+		Path:      path,
+		Cols:      header.Width,
+		Rows:      header.Height,
+		StartedAt: header.Timestamp,
+		Size:      stat.Size(),
+	}
+	if header.GoTerm != nil {
+		item.TermID = header.GoTerm.TermID
+		item.Input = header.GoTerm.Input
+	}
+	item.Duration = lastEventTime(file, stat.Size())
+	return item, nil
+}
+
+// lastEventTime reads the tail of a recording to find the time of its final
+// event without scanning the whole file.
+func lastEventTime(file *os.File, size int64) float64 {
+	const tail = 64 * 1024
+	offset := size - tail
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, size-offset)
+	if _, err := file.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return 0
+	}
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var event []json.RawMessage
+		if err := json.Unmarshal(lines[i], &event); err != nil || len(event) == 0 {
+			continue
+		}
+		var at float64
+		if err := json.Unmarshal(event[0], &at); err == nil {
+			return at
+		}
+	}
+	return 0
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,94 @@
+package terminal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AutoRecordRule records every terminal opened on a profile in Group. An
+// empty group matches profiles without one; "*" matches every profile.
+type AutoRecordRule struct {
+	Group string `json:"group"`
+	Input bool   `json:"input"`
+}
+
+// RecordRules keeps the auto-record rules in a JSON file.
+type RecordRules struct {
+	path string
+
+	mu    sync.Mutex
+	rules []AutoRecordRule
+}
+
+func NewRecordRules(path string) (*RecordRules, error) {
+	r := &RecordRules{path: path, rules: []AutoRecordRule{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.rules); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RecordRules) List() []AutoRecordRule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AutoRecordRule{}, r.rules...)
+}
+
+// Set replaces all rules. Rules for the same group are collapsed, recording
+// input if any of them asked for it.
+func (r *RecordRules) Set(rules []AutoRecordRule) error {
+	merged := []AutoRecordRule{}
+	index := map[string]int{}
+	for _, rule := range rules {
+		rule.Group = strings.TrimSpace(rule.Group)
+		if i, ok := index[rule.Group]; ok {
+			merged[i].Input = merged[i].Input || rule.Input
+			continue
+		}
+		index[rule.Group] = len(merged)
+		merged = append(merged, rule)
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.WriteFile(r.path, data, 0o600); err != nil {
+		return err
+	}
+	r.rules = merged
+	return nil
+}
+
+// Match returns the recording options for a profile in group, if any rule
+// applies.
+func (r *RecordRules) Match(group string) (RecordOptions, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	group = strings.TrimSpace(group)
+	matched := false
+	var opts RecordOptions
+	for _, rule := range r.rules {
+		if rule.Group == "*" || rule.Group == group {
+			matched = true
+			opts.Input = opts.Input || rule.Input
+		}
+	}
+	return opts, matched
+}
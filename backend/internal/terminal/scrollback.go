@@ -0,0 +1,62 @@
+package terminal
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+const defaultScrollback = 1024 * 1024
+
+// Snapshot is the buffered output of a terminal. Seq is the sequence number of
+// the last chunk included; live "terminal:data" events with a Seq at or below
+// it are already part of Data.
+type Snapshot struct {
+	TermID    string `json:"termId"`
+	SessionID string `json:"sessionId"`
+	Cols      int    `json:"cols"`
+	Rows      int    `json:"rows"`
+	Data      string `json:"data"`
+	Seq       uint64 `json:"seq"`
+	Truncated bool   `json:"truncated"`
+}
+
+// scrollback keeps the most recent output of a terminal, up to limit bytes.
+type scrollback struct {
+	mu        sync.Mutex
+	data      []byte
+	limit     int
+	seq       uint64
+	truncated bool
+}
+
+func newScrollback(limit int) *scrollback {
+	return &scrollback{limit: limit}
+}
+
+// write appends chunk and returns its sequence number.
+func (s *scrollback) write(chunk []byte) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	if s.limit <= 0 {
+		return s.seq
+	}
+	s.data = append(s.data, chunk...)
+	// Trim in batches so a busy terminal does not copy the buffer on every
+	// chunk.
+	if len(s.data) > s.limit+s.limit/4 {
+		cut := len(s.data) - s.limit
+		for cut < len(s.data) && !utf8.RuneStart(s.data[cut]) {
+			cut++
+		}
+		s.data = append(s.data[:0], s.data[cut:]...)
+		s.truncated = true
+	}
+	return s.seq
+}
+
+func (s *scrollback) snapshot() (string, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return string(s.data), s.seq, s.truncated
+}
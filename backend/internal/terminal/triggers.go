@@ -0,0 +1,374 @@
+package terminal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"goterm/backend/internal/common"
+)
+
+const (
+	TriggerNotify  = "notify"
+	TriggerRespond = "respond"
+	TriggerRecord  = "record"
+	TriggerMark    = "mark"
+
+	// AllProfiles keys rules that apply to every profile.
+	AllProfiles = "*"
+
+	defaultTriggerCooldown = 2 * time.Second
+	// triggerOverlap is how much earlier output is kept so a match can span
+	// two chunks. Longer matches are only found within one chunk.
+	triggerOverlap = 1024
+	// maxResponsesPerMinute stops auto-responses that feed each other.
+	maxResponsesPerMinute = 10
+	maxMatchLength        = 256
+)
+
+// TriggerRule reacts to output matching Pattern. Response is written as is for
+// the respond action; end it with "\r" to press Enter.
+type TriggerRule struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Pattern         string `json:"pattern"`
+	Action          string `json:"action"`
+	Response        string `json:"response"`
+	Enabled         bool   `json:"enabled"`
+	CooldownSeconds int    `json:"cooldownSeconds"`
+}
+
+// TriggerEvent is emitted as "trigger:fired" for every rule that fired.
+type TriggerEvent struct {
+	TermID   string `json:"termId"`
+	RuleID   string `json:"ruleId"`
+	RuleName string `json:"ruleName"`
+	Action   string `json:"action"`
+	Match    string `json:"match"`
+	Time     int64  `json:"time"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Mark is a point in a terminal's output tagged by a trigger. Seq is the
+// output chunk it was found in.
+type Mark struct {
+	Time  int64  `json:"time"`
+	Seq   uint64 `json:"seq"`
+	Label string `json:"label"`
+}
+
+// TriggerRules keeps the trigger rules of each profile in a JSON file.
+type TriggerRules struct {
+	path string
+
+	mu    sync.Mutex
+	rules map[string][]TriggerRule
+}
+
+func NewTriggerRules(path string) (*TriggerRules, error) {
+	r := &TriggerRules{path: path, rules: map[string][]TriggerRule{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.rules); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// List returns the rules stored for profileID, or the global ones for
+// AllProfiles.
+func (r *TriggerRules) List(profileID string) []TriggerRule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TriggerRule{}, r.rules[profileID]...)
+}
+
+// ForProfile returns the global rules followed by the profile's own.
+func (r *TriggerRules) ForProfile(profileID string) []TriggerRule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rules := append([]TriggerRule{}, r.rules[AllProfiles]...)
+	if profileID != AllProfiles {
+		rules = append(rules, r.rules[profileID]...)
+	}
+	return rules
+}
+
+// Set replaces the rules of profileID after checking them.
+func (r *TriggerRules) Set(profileID string, rules []TriggerRule) error {
+	if profileID == "" {
+		return errors.New("profile is required")
+	}
+	checked := make([]TriggerRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			id, err := common.NewID()
+			if err != nil {
+				return err
+			}
+			rule.ID = id
+		}
+		if _, err := compileTrigger(rule); err != nil {
+			return err
+		}
+		checked = append(checked, rule)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := map[string][]TriggerRule{}
+	for key, value := range r.rules {
+		next[key] = value
+	}
+	if len(checked) == 0 {
+		delete(next, profileID)
+	} else {
+		next[profileID] = checked
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0o600); err != nil {
+		return err
+	}
+	r.rules = next
+	return nil
+}
+
+type compiledTrigger struct {
+	rule     TriggerRule
+	re       *regexp.Regexp
+	cooldown time.Duration
+	last     time.Time
+}
+
+func compileTrigger(rule TriggerRule) (*compiledTrigger, error) {
+	switch rule.Action {
+	case TriggerNotify, TriggerRecord, TriggerMark:
+	case TriggerRespond:
+		if rule.Response == "" {
+			return nil, fmt.Errorf("trigger %q needs a response", rule.Name)
+		}
+	default:
+		return nil, fmt.Errorf("trigger %q has unknown action %q", rule.Name, rule.Action)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("trigger %q: %w", rule.Name, err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("trigger %q matches empty output", rule.Name)
+	}
+	cooldown := defaultTriggerCooldown
+	if rule.CooldownSeconds > 0 {
+		cooldown = time.Duration(rule.CooldownSeconds) * time.Second
+	}
+	return &compiledTrigger{rule: rule, re: re, cooldown: cooldown}, nil
+}
+
+type triggerHit struct {
+	rule  TriggerRule
+	match string
+}
+
+// triggerEngine matches one terminal's output against its rules. Cooldowns
+// and the response budget are shared by the terminal's streams and survive
+// rule reloads.
+type triggerEngine struct {
+	mu        sync.Mutex
+	triggers  []*compiledTrigger
+	responses []time.Time
+}
+
+// triggerStream is the per-stream matching state. Output is matched without
+// escape sequences, together with the tail of the previous chunk so matches
+// that span chunks are found once.
+type triggerStream struct {
+	escapes escapeStripper
+	tail    string
+}
+
+func newTriggerEngine(rules []TriggerRule) *triggerEngine {
+	engine := &triggerEngine{}
+	engine.load(rules)
+	return engine
+}
+
+// load replaces the rules. A rule that is kept keeps its cooldown.
+func (e *triggerEngine) load(rules []TriggerRule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	last := map[string]time.Time{}
+	for _, trigger := range e.triggers {
+		last[trigger.rule.ID] = trigger.last
+	}
+	e.triggers = nil
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		// Rules were checked when saved; skip any that no longer compile.
+		if trigger, err := compileTrigger(rule); err == nil {
+			trigger.last = last[rule.ID]
+			e.triggers = append(e.triggers, trigger)
+		}
+	}
+}
+
+func (e *triggerEngine) scan(stream *triggerStream, chunk string, now time.Time) []triggerHit {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.triggers) == 0 {
+		return nil
+	}
+
+	text := stream.escapes.strip(chunk)
+	if text == "" {
+		return nil
+	}
+	window := stream.tail + text
+	offset := len(stream.tail)
+	stream.tail = tailOf(window, triggerOverlap)
+
+	var hits []triggerHit
+	for _, trigger := range e.triggers {
+		var match string
+		for _, loc := range trigger.re.FindAllStringIndex(window, -1) {
+			// Matches ending in the old tail were handled with the last chunk.
+			if loc[1] > offset {
+				match = window[loc[0]:loc[1]]
+				break
+			}
+		}
+		if match == "" || now.Sub(trigger.last) < trigger.cooldown {
+			continue
+		}
+		if trigger.rule.Action == TriggerRespond && !e.allowResponse(now) {
+			continue
+		}
+		trigger.last = now
+		hits = append(hits, triggerHit{rule: trigger.rule, match: truncate(match, maxMatchLength)})
+	}
+	return hits
+}
+
+func (e *triggerEngine) allowResponse(now time.Time) bool {
+	recent := e.responses[:0]
+	for _, at := range e.responses {
+		if now.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	e.responses = recent
+	if len(e.responses) >= maxResponsesPerMinute {
+		return false
+	}
+	e.responses = append(e.responses, now)
+	return true
+}
+
+func tailOf(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := len(s) - n
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return s[cut:]
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut])
+}
+
+// Marks returns the points a mark trigger tagged in the terminal's output.
+func (h *Hub) Marks(termID string) ([]Mark, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	term, ok := h.terms[termID]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	return append([]Mark{}, term.marks...), nil
+}
+
+func (h *Hub) fire(termID string, seq uint64, hits []triggerHit) {
+	for _, hit := range hits {
+		event := TriggerEvent{
+			TermID:   termID,
+			RuleID:   hit.rule.ID,
+			RuleName: hit.rule.Name,
+			Action:   hit.rule.Action,
+			Match:    hit.match,
+			Time:     time.Now().Unix(),
+		}
+		if err := h.apply(termID, seq, hit); err != nil {
+			event.Error = err.Error()
+		}
+		h.emitter.Emit("trigger:fired", event)
+	}
+}
+
+func (h *Hub) apply(termID string, seq uint64, hit triggerHit) error {
+	switch hit.rule.Action {
+	case TriggerRespond:
+		// Answer only this terminal, even if it is part of a broadcast group.
+		term, err := h.get(termID)
+		if err != nil {
+			return err
+		}
+		return h.writeAll([]*Terminal{term}, hit.rule.Response)
+	case TriggerRecord:
+		if _, err := h.StartRecording(termID, RecordOptions{Title: hit.rule.Name}); err != nil {
+			if errors.Is(err, errAlreadyRecording) {
+				return nil
+			}
+			return err
+		}
+	case TriggerMark:
+		label := hit.rule.Name
+		if label == "" {
+			label = hit.match
+		}
+		h.mu.Lock()
+		term, ok := h.terms[termID]
+		var rec *recorder
+		if ok {
+			term.marks = append(term.marks, Mark{Time: time.Now().Unix(), Seq: seq, Label: label})
+			rec = term.rec
+		}
+		h.mu.Unlock()
+		if !ok {
+			return common.ErrNotFound
+		}
+		if rec != nil {
+			rec.marker(label)
+		}
+	}
+	return nil
+}